@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/utkuufuk/entrello/internal/auth"
+)
+
+func runApiKey(cmd string, args []string) error {
+	if cmd != "generate" || len(args) != 2 {
+		return fmt.Errorf("usage: entrello apikey generate <name> <scope>[+<scope>...]")
+	}
+
+	secret, hash, err := auth.GenerateKey()
+	if err != nil {
+		return fmt.Errorf("could not generate API key: %w", err)
+	}
+
+	fmt.Printf("API key (shown only once): %s\n", secret)
+	fmt.Printf("API_KEYS entry: %s:%s:%s\n", args[0], hash, args[1])
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/internal/outbox"
+)
+
+const usage = `Usage:
+  entrello deadletters list            list the notifications that could not be delivered
+  entrello deadletters replay <id>...  schedule the given dead letters for redelivery
+  entrello deadletters replay --all    schedule all dead letters for redelivery
+
+  entrello webhooks list [-c <config>]
+      list the webhooks of the Trello API token
+  entrello webhooks create [-c <config>] [-url <callback>] [-description <text>]
+      create a webhook for the Trello board, the callback URL defaults to TRELLO_WEBHOOK_CALLBACK_URL
+  entrello webhooks delete [-c <config>] <id>...
+      delete the given webhooks
+
+  entrello apikey generate <name> <scope>[+<scope>...]
+      generate a new API key with the given scopes (sync, admin, read-only),
+      and print it along with its entry for the API_KEYS environment variable
+
+  Trello credentials are read from the given config file if the -c flag is present,
+  or from the TRELLO_API_KEY, TRELLO_API_TOKEN and TRELLO_BOARD_ID environment variables otherwise.
+`
+
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "deadletters":
+		err = runDeadLetters(os.Args[2], os.Args[3:])
+	case "webhooks":
+		err = runWebhooks(os.Args[2], os.Args[3:])
+	case "apikey":
+		err = runApiKey(os.Args[2], os.Args[3:])
+	default:
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func runDeadLetters(cmd string, args []string) error {
+	box, err := outbox.New(config.ServerCfg.OutboxDir, config.ServerCfg.OutboxMaxAttempts, 0)
+	if err != nil {
+		return err
+	}
+
+	switch cmd {
+	case "list":
+		messages, err := box.DeadLetters()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tCREATED\tEVENT\tSERVICE\tATTEMPTS\tLAST ERROR")
+		for _, msg := range messages {
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%s\t%s\t%d\t%s\n",
+				msg.Id,
+				msg.CreatedAt.Format(time.RFC3339),
+				msg.Event,
+				msg.ServiceKey,
+				msg.Attempts,
+				msg.LastError,
+			)
+		}
+		return w.Flush()
+
+	case "replay":
+		if len(args) == 0 {
+			return fmt.Errorf("missing dead letter ID(s)")
+		}
+
+		ids := args
+		if len(args) == 1 && args[0] == "--all" {
+			messages, err := box.DeadLetters()
+			if err != nil {
+				return err
+			}
+			ids = make([]string, 0, len(messages))
+			for _, msg := range messages {
+				ids = append(ids, msg.Id)
+			}
+		}
+
+		for _, id := range ids {
+			if err = box.Replay(id); err != nil {
+				return fmt.Errorf("could not replay dead letter %s: %w", id, err)
+			}
+			fmt.Println("Replaying", id)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown deadletters command: %s", cmd)
+}
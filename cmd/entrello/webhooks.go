@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/pkg/trello"
+)
+
+func runWebhooks(cmd string, args []string) error {
+	flags := flag.NewFlagSet("webhooks "+cmd, flag.ExitOnError)
+	configFile := flags.String("c", "", "config file path")
+	callbackUrl := flags.String("url", config.ServerCfg.TrelloWebhookCallbackUrl, "webhook callback URL")
+	description := flags.String("description", config.DefaultWebhookDescription, "webhook description")
+	flags.Parse(args)
+
+	cfg, err := readTrelloConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	client := trello.NewClient(cfg)
+
+	switch cmd {
+	case "list":
+		webhooks, err := client.GetWebhooks()
+		if err != nil {
+			return fmt.Errorf("could not list webhooks: %w", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tMODEL\tACTIVE\tCALLBACK URL\tDESCRIPTION")
+		for _, webhook := range webhooks {
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%t\t%s\t%s\n",
+				webhook.ID,
+				webhook.IDModel,
+				webhook.Active,
+				webhook.CallbackURL,
+				webhook.Description,
+			)
+		}
+		return w.Flush()
+
+	case "create":
+		if *callbackUrl == "" {
+			return fmt.Errorf("missing callback URL, either use the -url flag or set TRELLO_WEBHOOK_CALLBACK_URL")
+		}
+		if cfg.BoardId == "" {
+			return fmt.Errorf("missing Trello board ID")
+		}
+
+		webhook, err := client.CreateWebhook(*callbackUrl, *description)
+		if err != nil {
+			return fmt.Errorf("could not create webhook: %w", err)
+		}
+		fmt.Println("Created webhook", webhook.ID)
+		return nil
+
+	case "delete":
+		if flags.NArg() == 0 {
+			return fmt.Errorf("missing webhook ID(s)")
+		}
+
+		for _, id := range flags.Args() {
+			if err = client.DeleteWebhook(id); err != nil {
+				return fmt.Errorf("could not delete webhook %s: %w", id, err)
+			}
+			fmt.Println("Deleted webhook", id)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown webhooks command: %s", cmd)
+}
+
+// readTrelloConfig reads the Trello credentials from the given config file, or from the
+// environment variables if the file name is empty
+func readTrelloConfig(configFile string) (config.Trello, error) {
+	if configFile != "" {
+		cfg, err := config.ReadRunnerConfig(configFile)
+		if err != nil {
+			return config.Trello{}, fmt.Errorf("could not read configuration: %w", err)
+		}
+		return cfg.Trello, nil
+	}
+
+	cfg := config.ServerCfg.Trello()
+	if cfg.ApiKey == "" || cfg.ApiToken == "" {
+		return cfg, fmt.Errorf(
+			"missing Trello credentials, either use the -c flag or set TRELLO_API_KEY and TRELLO_API_TOKEN",
+		)
+	}
+	return cfg, nil
+}
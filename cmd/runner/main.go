@@ -1,25 +1,139 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
 	"flag"
+	"fmt"
+	"io/ioutil"
 	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 
 	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/internal/lock"
 	"github.com/utkuufuk/entrello/internal/logger"
+	"github.com/utkuufuk/entrello/internal/metrics"
 	"github.com/utkuufuk/entrello/internal/services"
 )
 
 func main() {
-	var configFile string
+	var configFile, service, lockFile, metricsFile, metricsPushUrl string
 	flag.StringVar(&configFile, "c", "config.json", "config file path")
+	flag.StringVar(&service, "service", "", "name of a single service to poll regardless of its period")
+	flag.StringVar(&lockFile, "lock", "", "lock file path, defaults to a file in the temp directory unique to the config file")
+	flag.StringVar(&metricsFile, "metrics-file", "", "file to dump the metrics into in the Prometheus text format")
+	flag.StringVar(&metricsPushUrl, "metrics-push-url", "", "Prometheus Pushgateway URL to push the metrics to")
 	flag.Parse()
 
+	if lockFile == "" {
+		lockFile = defaultLockFile(configFile)
+	}
+
+	// prevent overlapping invocations (e.g. consecutive cron jobs) from creating duplicate cards
+	l, err := lock.Acquire(lockFile)
+	if errors.Is(err, lock.ErrLocked) {
+		logger.Warn("Another runner is already running with the lock file %s, exiting", lockFile)
+		return
+	}
+	if err != nil {
+		log.Fatalf("Could not acquire lock: %v", err)
+	}
+	defer l.Release()
+
 	cfg, err := config.ReadRunnerConfig(configFile)
 	if err != nil {
 		log.Fatalf("Could not read configuration: %v", err)
 	}
 
-	if err = services.Poll(cfg); err != nil {
-		logger.Error(err.Error())
+	// abort the API calls in progress upon SIGTERM or SIGINT, so that the state is still saved
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	ok := poll(ctx, cfg, service)
+	exportMetrics(metricsFile, metricsPushUrl)
+	if !ok {
+		// exit with a non-zero status so that schedulers such as cron can tell the failures apart,
+		// where the lock is released by the OS upon exit
+		os.Exit(1)
+	}
+}
+
+// poll polls either the services that should be polled at this time instant or the service with the
+// given name only, and reports whether every poll has succeeded without any errors
+func poll(ctx context.Context, cfg config.RunnerConfig, service string) bool {
+	if service == "" {
+		reports, err := services.Poll(ctx, cfg)
+		if err != nil {
+			logger.Error(err.Error())
+			return false
+		}
+		for _, report := range reports {
+			if len(report.Errors) > 0 {
+				return false
+			}
+		}
+		return true
+	}
+
+	report, err := services.PollService(ctx, cfg, service)
+	if err != nil {
+		logger.Error("Could not poll service '%s': %v", service, err)
+		return false
+	}
+
+	logger.Info(
+		"Polled service '%s': %d created, %d deleted, %d invalid, %d errors",
+		report.Service,
+		len(report.Created),
+		len(report.Deleted),
+		report.Invalid,
+		len(report.Errors),
+	)
+	return len(report.Errors) == 0
+}
+
+// defaultLockFile returns a lock file path in the temp directory that is unique to the given config
+// file, so that the config file may reside in a read-only directory (e.g. a mounted ConfigMap)
+func defaultLockFile(configFile string) string {
+	if abs, err := filepath.Abs(configFile); err == nil {
+		configFile = abs
+	}
+	hash := sha256.Sum256([]byte(configFile))
+	return filepath.Join(os.TempDir(), fmt.Sprintf("entrello-%x.lock", hash[:8]))
+}
+
+// exportMetrics dumps the metrics into the given file and pushes them to the given Pushgateway URL,
+// skipping either one if it's empty
+func exportMetrics(file, pushUrl string) {
+	if file != "" {
+		if err := writeMetrics(file); err != nil {
+			logger.Error("Could not dump metrics: %v", err)
+		}
+	}
+
+	if pushUrl != "" {
+		if err := metrics.Default.Push(pushUrl); err != nil {
+			logger.Error(err.Error())
+		}
+	}
+}
+
+// writeMetrics writes the metrics into the given file atomically, so that a collector such as
+// the node exporter never reads a partially written file
+func writeMetrics(file string) error {
+	var buf bytes.Buffer
+	if err := metrics.Default.Write(&buf); err != nil {
+		return err
+	}
+
+	tmp := file + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
 	}
+	return os.Rename(tmp, file)
 }
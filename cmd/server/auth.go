@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/utkuufuk/entrello/internal/auth"
+	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/internal/logger"
+)
+
+const (
+	maxAuthFailures   = 5
+	authFailureWindow = time.Minute
+)
+
+var authLimiter = auth.NewLimiter(maxAuthFailures, authFailureWindow)
+
+// legacyKey represents the basic auth credentials of the USERNAME and PASSWORD environment variables,
+// which are limited to the synchronization and read-only endpoints they were used for before API keys
+var legacyKey = config.ApiKey{Name: "basic-auth", Scopes: []string{config.ScopeSync, config.ScopeReadOnly}}
+
+// authorize authenticates the request and checks that its API key has any of the given scopes.
+// Responds with 429 if the client has failed to authenticate too many times recently, 401 if the
+// credentials are invalid, or 403 if the API key lacks the scopes. The credentials are never logged.
+func authorize(w http.ResponseWriter, req *http.Request, scopes ...string) bool {
+	client := clientIp(req)
+	if !authLimiter.Allow(client) {
+		logger.Warn("Too many failed authentication attempts from %s", client)
+		w.Header().Set("Retry-After", strconv.Itoa(int(authFailureWindow.Seconds())))
+		w.WriteHeader(http.StatusTooManyRequests)
+		return false
+	}
+
+	key, ok := authenticate(req)
+	if !ok {
+		// browsers send the first request without credentials and prompt for them upon the challenge,
+		// so only the rejected credentials count as failed attempts
+		if hasCredentials(req) {
+			authLimiter.Fail(client)
+		}
+		logger.Warn("Invalid or missing credentials for %s %s from %s", req.Method, req.URL.Path, client)
+		// let browsers prompt for the basic auth credentials, e.g. on the dashboard
+		w.Header().Set("WWW-Authenticate", `Basic realm="entrello", charset="UTF-8"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return false
+	}
+
+	if !auth.HasScope(key, scopes...) {
+		logger.Warn("API key '%s' is not allowed to %s %s", key.Name, req.Method, req.URL.Path)
+		w.WriteHeader(http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// authenticate returns the API key of the request, which is either given as a bearer token, in the
+// X-Api-Key header, or as the basic auth password. The basic auth credentials of the USERNAME and
+// PASSWORD environment variables are also accepted with the sync and read-only scopes for backwards
+// compatibility.
+func authenticate(req *http.Request) (config.ApiKey, bool) {
+	secret := req.Header.Get("X-Api-Key")
+	if header := req.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		secret = strings.TrimPrefix(header, "Bearer ")
+	}
+
+	user, pwd, isBasic := req.BasicAuth()
+	if isBasic {
+		secret = pwd
+	}
+
+	if secret == "" {
+		return config.ApiKey{}, false
+	}
+
+	if key, ok := auth.Authenticate(config.ServerCfg.ApiKeys, secret); ok {
+		return key, true
+	}
+
+	if isBasic && config.ServerCfg.Username != "" && config.ServerCfg.Password != "" {
+		// compare the hashes so that the comparison takes the same time regardless of the lengths
+		userOk := subtle.ConstantTimeCompare(auth.Hash(user), auth.Hash(config.ServerCfg.Username))
+		pwdOk := subtle.ConstantTimeCompare(auth.Hash(pwd), auth.Hash(config.ServerCfg.Password))
+		if userOk&pwdOk == 1 {
+			return legacyKey, true
+		}
+	}
+	return config.ApiKey{}, false
+}
+
+// hasCredentials checks if the request carries any credentials, valid or not
+func hasCredentials(req *http.Request) bool {
+	return req.Header.Get("Authorization") != "" || req.Header.Get("X-Api-Key") != ""
+}
+
+// clientIp returns the IP address of the client, taken from the X-Forwarded-For header appended by
+// the reverse proxy if the proxy is trusted
+func clientIp(req *http.Request) string {
+	if config.ServerCfg.TrustProxy {
+		if forwarded := req.Header.Get("X-Forwarded-For"); forwarded != "" {
+			ips := strings.Split(forwarded, ",")
+			return strings.TrimSpace(ips[len(ips)-1])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/utkuufuk/entrello/internal/auth"
+	"github.com/utkuufuk/entrello/internal/config"
+)
+
+const (
+	cronSecret = "cron-secret"
+	opsSecret  = "ops-secret"
+)
+
+// withAuthConfig sets the credentials of the server along with a fresh rate limiter for the test
+func withAuthConfig(t *testing.T, trustProxy bool) {
+	saved, savedLimiter := config.ServerCfg, authLimiter
+	t.Cleanup(func() { config.ServerCfg, authLimiter = saved, savedLimiter })
+
+	authLimiter = auth.NewLimiter(maxAuthFailures, authFailureWindow)
+	config.ServerCfg = config.ServerConfig{
+		Username:   "user",
+		Password:   "pass",
+		TrustProxy: trustProxy,
+		ApiKeys: []config.ApiKey{
+			{Name: "cron", Hash: hex.EncodeToString(auth.Hash(cronSecret)), Scopes: []string{config.ScopeSync}},
+			{Name: "ops", Hash: hex.EncodeToString(auth.Hash(opsSecret)), Scopes: []string{config.ScopeAdmin}},
+			{Name: "broken", Hash: "not-hex", Scopes: []string{config.ScopeAdmin}},
+		},
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	tt := []struct {
+		name    string
+		headers map[string]string
+		user    string
+		pwd     string
+		want    string
+	}{
+		{
+			name:    "bearer token",
+			headers: map[string]string{"Authorization": "Bearer " + cronSecret},
+			want:    "cron",
+		},
+		{
+			name:    "api key header",
+			headers: map[string]string{"X-Api-Key": opsSecret},
+			want:    "ops",
+		},
+		{
+			name: "basic auth password",
+			user: "anyone",
+			pwd:  opsSecret,
+			want: "ops",
+		},
+		{
+			name: "legacy basic auth credentials",
+			user: "user",
+			pwd:  "pass",
+			want: legacyKey.Name,
+		},
+		{
+			name: "legacy basic auth with wrong username",
+			user: "admin",
+			pwd:  "pass",
+		},
+		{
+			name: "legacy basic auth with prefix of password",
+			user: "user",
+			pwd:  "pas",
+		},
+		{
+			name:    "unknown key",
+			headers: map[string]string{"Authorization": "Bearer unknown"},
+		},
+		{
+			name:    "revoked key",
+			headers: map[string]string{"X-Api-Key": "revoked-secret"},
+		},
+		{
+			name:    "hash instead of key",
+			headers: map[string]string{"X-Api-Key": hex.EncodeToString(auth.Hash(cronSecret))},
+		},
+		{
+			name:    "key with an invalid hash",
+			headers: map[string]string{"X-Api-Key": "not-hex"},
+		},
+		{
+			name: "no credentials",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			withAuthConfig(t, false)
+
+			req := httptest.NewRequest(http.MethodGet, "/status", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			if tc.user != "" || tc.pwd != "" {
+				req.SetBasicAuth(tc.user, tc.pwd)
+			}
+
+			key, ok := authenticate(req)
+			if ok != (tc.want != "") || key.Name != tc.want {
+				t.Errorf("wanted key '%s', got '%s' (ok: %t)", tc.want, key.Name, ok)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	tt := []struct {
+		name   string
+		secret string
+		basic  bool
+		scopes []string
+		status int
+	}{
+		{
+			name:   "key with the scope",
+			secret: cronSecret,
+			scopes: []string{config.ScopeSync},
+			status: http.StatusOK,
+		},
+		{
+			name:   "key with any of the scopes",
+			secret: cronSecret,
+			scopes: []string{config.ScopeSync, config.ScopeReadOnly},
+			status: http.StatusOK,
+		},
+		{
+			name:   "key without the scope",
+			secret: cronSecret,
+			scopes: []string{config.ScopeReadOnly},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "admin key",
+			secret: opsSecret,
+			scopes: []string{config.ScopeReadOnly},
+			status: http.StatusOK,
+		},
+		{
+			name:   "legacy basic auth credentials with sync scope",
+			basic:  true,
+			scopes: []string{config.ScopeSync},
+			status: http.StatusOK,
+		},
+		{
+			name:   "legacy basic auth credentials with read-only scope",
+			basic:  true,
+			scopes: []string{config.ScopeReadOnly},
+			status: http.StatusOK,
+		},
+		{
+			name:   "legacy basic auth credentials without admin scope",
+			basic:  true,
+			scopes: []string{config.ScopeAdmin},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "unknown key",
+			secret: "unknown",
+			scopes: []string{config.ScopeReadOnly},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "no credentials",
+			scopes: []string{config.ScopeReadOnly},
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			withAuthConfig(t, false)
+
+			req := httptest.NewRequest(http.MethodGet, "/status", nil)
+			if tc.secret != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.secret)
+			}
+			if tc.basic {
+				req.SetBasicAuth("user", "pass")
+			}
+
+			w := httptest.NewRecorder()
+			ok := authorize(w, req, tc.scopes...)
+			if ok != (tc.status == http.StatusOK) {
+				t.Errorf("wanted authorized to be %t, got %t", tc.status == http.StatusOK, ok)
+			}
+			if !ok && w.Code != tc.status {
+				t.Errorf("wanted status code %d, got %d", tc.status, w.Code)
+			}
+			if tc.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("wanted a basic auth challenge")
+			}
+		})
+	}
+}
+
+func TestAuthorizeRateLimit(t *testing.T) {
+	withAuthConfig(t, false)
+
+	request := func(secret string) int {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		if secret != "" {
+			req.Header.Set("X-Api-Key", secret)
+		}
+		w := httptest.NewRecorder()
+		authorize(w, req, config.ScopeReadOnly)
+		return w.Code
+	}
+
+	// requests without credentials don't count as failed attempts
+	for i := 0; i < maxAuthFailures+1; i++ {
+		if code := request(""); code != http.StatusUnauthorized {
+			t.Fatalf("wanted status code %d without credentials, got %d", http.StatusUnauthorized, code)
+		}
+	}
+
+	for i := 0; i < maxAuthFailures; i++ {
+		if code := request("wrong"); code != http.StatusUnauthorized {
+			t.Fatalf("wanted status code %d for attempt %d, got %d", http.StatusUnauthorized, i+1, code)
+		}
+	}
+
+	if code := request(opsSecret); code != http.StatusTooManyRequests {
+		t.Errorf("wanted status code %d after too many failures, got %d", http.StatusTooManyRequests, code)
+	}
+}
+
+func TestHasCredentials(t *testing.T) {
+	tt := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{name: "no headers", want: false},
+		{name: "authorization header", headers: map[string]string{"Authorization": "Basic Og=="}, want: true},
+		{name: "api key header", headers: map[string]string{"X-Api-Key": "secret"}, want: true},
+		{name: "unrelated header", headers: map[string]string{"Cookie": "a=b"}, want: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/status", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			if got := hasCredentials(req); got != tc.want {
+				t.Errorf("wanted %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestClientIp(t *testing.T) {
+	tt := []struct {
+		name       string
+		trustProxy bool
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{
+			name:       "remote address",
+			remoteAddr: "10.0.0.1:1234",
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "remote address without port",
+			remoteAddr: "10.0.0.1",
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "forwarded header ignored without trusted proxy",
+			remoteAddr: "10.0.0.1:1234",
+			forwarded:  "203.0.113.7",
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "forwarded header with trusted proxy",
+			trustProxy: true,
+			remoteAddr: "10.0.0.1:1234",
+			forwarded:  "203.0.113.7",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "last forwarded address appended by trusted proxy",
+			trustProxy: true,
+			remoteAddr: "10.0.0.1:1234",
+			forwarded:  "198.51.100.1, 203.0.113.7",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "no forwarded header with trusted proxy",
+			trustProxy: true,
+			remoteAddr: "10.0.0.1:1234",
+			want:       "10.0.0.1",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			withAuthConfig(t, tc.trustProxy)
+
+			req := httptest.NewRequest(http.MethodGet, "/status", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+			if got := clientIp(req); got != tc.want {
+				t.Errorf("wanted '%s', got '%s'", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/internal/history"
+	"github.com/utkuufuk/entrello/internal/logger"
+	"github.com/utkuufuk/entrello/internal/services"
+)
+
+//go:embed dashboard.html
+var dashboardHtml string
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"time": formatTime,
+}).Parse(dashboardHtml))
+
+// dashboardResults maps the result codes of the dashboard sync requests to the messages to display,
+// so that the redirect URLs can't be used for injecting arbitrary text into the dashboard
+var dashboardResults = map[string]string{
+	"synced":    "Sync completed.",
+	"dry-run":   "Dry run completed, see the recent events for the changes that would be made.",
+	"partial":   "Sync completed with errors, see the last result of the service.",
+	"conflict":  "A sync is already in progress for the board, try again later.",
+	"not-found": "Service not found in the config file of the server.",
+	"failed":    "Sync failed, see the server logs for details.",
+}
+
+// dashboardService represents a row of the services table, where only the services with a name
+// in the config file of the server can be synchronized from the dashboard
+type dashboardService struct {
+	history.Summary
+	Period   string
+	Syncable bool
+}
+
+type dashboardData struct {
+	Services []dashboardService
+	Events   []history.Event
+	Message  string
+}
+
+// handleDashboardRequest renders the dashboard, which lists the services with their latest run
+// along with the recent events
+func handleDashboardRequest(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		logger.Warn("Method %s not allowed for %s", req.Method, req.URL.Path)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorize(w, req, config.ScopeAdmin) {
+		return
+	}
+
+	data := dashboardData{
+		Services: dashboardServices(),
+		Events:   history.Default.Events(),
+		Message:  dashboardResults[req.URL.Query().Get("result")],
+	}
+
+	var b bytes.Buffer
+	if err := dashboardTemplate.Execute(&b, data); err != nil {
+		logger.Error("Could not render dashboard: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set(
+		"Content-Security-Policy",
+		"default-src 'none'; style-src 'unsafe-inline'; form-action 'self'; frame-ancestors 'none'",
+	)
+	w.WriteHeader(http.StatusOK)
+	w.Write(b.Bytes())
+}
+
+// handleDashboardSyncRequest synchronizes the service given in the form of a dashboard button,
+// optionally in dry-run mode, and redirects back to the dashboard with the result
+func handleDashboardSyncRequest(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		logger.Warn("Method %s not allowed for %s", req.Method, req.URL.Path)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorize(w, req, config.ScopeAdmin) {
+		return
+	}
+
+	if !isSameOrigin(req) {
+		logger.Warn("Rejecting cross-origin dashboard request from %s", req.Header.Get("Origin"))
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	name := req.PostFormValue("service")
+	dryRun := req.PostFormValue("dry_run") == "true"
+	http.Redirect(w, req, "/dashboard?result="+syncFromDashboard(req, name, dryRun), http.StatusSeeOther)
+}
+
+// syncFromDashboard synchronizes the service with the given name in the config file of the server,
+// and returns the result code to display on the dashboard
+func syncFromDashboard(req *http.Request, name string, dryRun bool) string {
+	cfg, err := readSyncConfig("")
+	if errors.Is(err, config.ErrProfileNotFound) {
+		return "not-found"
+	}
+	if err != nil {
+		logger.Error("Could not read sync configuration: %v", err)
+		return "failed"
+	}
+	cfg.DryRun = dryRun
+
+	report, err := services.PollService(req.Context(), cfg, name)
+	if errors.Is(err, services.ErrServiceNotFound) {
+		return "not-found"
+	}
+	if errors.Is(err, services.ErrSyncInProgress) {
+		logger.Warn("Rejecting dashboard sync request for service '%s': %v", name, err)
+		return "conflict"
+	}
+	if err != nil {
+		logger.Error("Dashboard sync failed for service '%s': %v", name, err)
+		return "failed"
+	}
+
+	switch {
+	case len(report.Errors) > 0:
+		return "partial"
+	case dryRun:
+		return "dry-run"
+	}
+	return "synced"
+}
+
+// dashboardServices returns the services in the config file of the server followed by any other
+// services that have been run, e.g. through sync profiles
+func dashboardServices() []dashboardService {
+	all := history.Default.Summaries()
+	summaries := make(map[string]history.Summary, len(all))
+	for _, summary := range all {
+		summaries[summary.Service] = summary
+	}
+
+	rows := make([]dashboardService, 0, len(summaries))
+	if config.ServerCfg.Sync != nil {
+		for _, service := range config.ServerCfg.Sync.Services {
+			key := service.Name
+			if key == "" {
+				key = service.Label
+			}
+
+			summary, ok := summaries[key]
+			if !ok {
+				summary = history.Summary{Service: key}
+			}
+			delete(summaries, key)
+
+			rows = append(rows, dashboardService{
+				Summary:  summary,
+				Period:   formatPeriod(service.Period),
+				Syncable: service.Name != "",
+			})
+		}
+	}
+
+	for _, summary := range all {
+		if _, ok := summaries[summary.Service]; ok {
+			rows = append(rows, dashboardService{Summary: summary, Period: "-"})
+		}
+	}
+	return rows
+}
+
+// formatPeriod describes how often a service is polled by the scheduled sync
+func formatPeriod(period config.Period) string {
+	switch period.Type {
+	case config.PeriodTypeDefault, "":
+		return "every minute"
+	case config.PeriodTypeDay, config.PeriodTypeHour, config.PeriodTypeMinute:
+		if period.Interval <= 1 {
+			return fmt.Sprintf("every %s", period.Type)
+		}
+		return fmt.Sprintf("every %d %ss", period.Interval, period.Type)
+	}
+	return period.Type
+}
+
+// isSameOrigin checks that a browser request has been made from the dashboard itself, so that other
+// sites can't trigger syncs with the cached credentials of an admin. Requests without the headers set
+// by browsers, e.g. from scripts, are allowed since they are not exposed to cross-site requests.
+func isSameOrigin(req *http.Request) bool {
+	if site := req.Header.Get("Sec-Fetch-Site"); site != "" {
+		return site == "same-origin" || site == "none"
+	}
+
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == req.Host
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.UTC().Format("2006-01-02 15:04:05 UTC")
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/internal/logger"
+	"github.com/utkuufuk/entrello/internal/services"
+)
+
+// check represents the outcome of a single readiness check
+type check struct {
+	Ok        bool      `json:"ok"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// readiness represents the breakdown of the readiness checks, where the services are checked
+// only if enabled by the configuration
+type readiness struct {
+	Ready    bool             `json:"ready"`
+	Config   check            `json:"config"`
+	Trello   check            `json:"trello"`
+	Services map[string]check `json:"services,omitempty"`
+}
+
+// cachedCheck runs a check at most once within the readiness cache TTL, so that frequent probes
+// don't hit the Trello API rate limits or the services
+type cachedCheck struct {
+	sync.Mutex
+	result check
+}
+
+func (c *cachedCheck) get(run func() error) check {
+	c.Lock()
+	defer c.Unlock()
+
+	if !c.result.CheckedAt.IsZero() && time.Since(c.result.CheckedAt) < config.ReadinessCacheTtl {
+		return c.result
+	}
+
+	c.result = check{Ok: true, CheckedAt: time.Now().UTC()}
+	if err := run(); err != nil {
+		c.result.Ok = false
+		c.result.Error = err.Error()
+	}
+	return c.result
+}
+
+var trelloCheck cachedCheck
+
+var serviceChecks = struct {
+	sync.Mutex
+	checks map[string]*cachedCheck
+}{checks: make(map[string]*cachedCheck)}
+
+// handleHealthRequest responds with 200 as long as the server is able to handle requests
+func handleHealthRequest(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		logger.Warn("Method %s not allowed for %s", req.Method, req.URL.Path)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJson(w, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"ok"})
+}
+
+// handleReadinessRequest responds with the breakdown of the readiness checks, with 200 if all of them
+// have passed, or 503 otherwise
+func handleReadinessRequest(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		logger.Warn("Method %s not allowed for %s", req.Method, req.URL.Path)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	r := readiness{Config: check{Ok: true, CheckedAt: time.Now().UTC()}}
+	if err := validateServerConfig(); err != nil {
+		r.Config = check{Ok: false, Error: err.Error(), CheckedAt: r.Config.CheckedAt}
+	}
+
+	r.Trello = trelloCheck.get(checkTrello)
+	r.Ready = r.Config.Ok && r.Trello.Ok
+
+	if config.ServerCfg.ReadinessCheckServices {
+		r.Services = checkServices()
+		for _, c := range r.Services {
+			r.Ready = r.Ready && c.Ok
+		}
+	}
+
+	if !r.Ready {
+		writeJson(w, http.StatusServiceUnavailable, r)
+		return
+	}
+	writeJson(w, http.StatusOK, r)
+}
+
+// checkTrello checks whether the Trello board is accessible within the readiness timeout, so that
+// a slow Trello API doesn't hold up the probes waiting for the cached result. The context is not
+// derived from the request, since the result is shared with the other probes.
+func checkTrello() error {
+	ctx, cancel := context.WithTimeout(context.Background(), config.ReadinessTimeout)
+	defer cancel()
+	return client.WithContext(ctx).CheckBoard()
+}
+
+// validateServerConfig checks whether the configuration contains the settings required by the server
+func validateServerConfig() error {
+	if config.ServerCfg.TrelloApiKey == "" || config.ServerCfg.TrelloApiToken == "" {
+		return fmt.Errorf("Trello API key or token is missing")
+	}
+	if config.ServerCfg.TrelloBoardId == "" {
+		return fmt.Errorf("Trello board ID is missing")
+	}
+	return nil
+}
+
+// checkServices checks concurrently whether the endpoints of the configured services are reachable,
+// where the results are keyed by the service name, or the endpoint host and path if it has no name
+func checkServices() map[string]check {
+	all := config.ServerCfg.Services
+	if config.ServerCfg.Sync != nil {
+		all = append(append([]config.Service{}, all...), config.ServerCfg.Sync.Services...)
+	}
+
+	results := make(map[string]check)
+	seen := make(map[string]bool)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, service := range all {
+		key := serviceKey(service)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		serviceChecks.Lock()
+		c, ok := serviceChecks.checks[key]
+		if !ok {
+			c = &cachedCheck{}
+			serviceChecks.checks[key] = c
+		}
+		serviceChecks.Unlock()
+
+		wg.Add(1)
+		go func(service config.Service, key string, c *cachedCheck) {
+			defer wg.Done()
+			result := c.get(func() error {
+				return services.CheckEndpoint(service, config.ReadinessTimeout)
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[key] = result
+		}(service, key, c)
+	}
+	wg.Wait()
+	return results
+}
+
+// serviceKey identifies a service in the readiness breakdown without exposing any credentials
+// that may be in the query string of its endpoint
+func serviceKey(service config.Service) string {
+	if service.Name != "" {
+		return service.Name
+	}
+
+	u, err := url.Parse(service.Endpoint)
+	if err != nil {
+		return "invalid endpoint"
+	}
+	return u.Host + u.Path
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/internal/logger"
+	"github.com/utkuufuk/entrello/internal/services"
+)
+
+// isAsync checks if the client has asked for the sync to run in the background
+func isAsync(req *http.Request) bool {
+	return req.URL.Query().Get("async") == "true"
+}
+
+// startSyncJob runs the sync in the background, and responds with 202 and the job, whose status can be
+// retrieved from the URL in the Location header, or with 409 if the board is already being synchronized
+func startSyncJob(w http.ResponseWriter, cfg config.RunnerConfig) {
+	// claim the board upfront, so that overlapping syncs are rejected just like the synchronous ones
+	claim, err := services.ClaimBoard(cfg.Trello.BoardId)
+	if err != nil {
+		logger.Warn("Rejecting sync job: %v", err)
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	job, err := syncJobs.Start(func(progress func(done, total int)) ([]services.Report, error) {
+		// the job outlives the request, so it's bound to the server instead
+		return services.PollWithProgress(workCtx, cfg, claim, progress)
+	})
+	if err != nil {
+		claim.Release()
+		logger.Error("Could not start sync job: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Started sync job %s", job.Id)
+	w.Header().Set("Location", "/jobs/"+job.Id)
+	writeJson(w, http.StatusAccepted, job)
+}
+
+// handleJobRequest responds with the progress of the sync job given in the path, i.e. GET /jobs/{id},
+// including the report of each polled service once the job is finished
+func handleJobRequest(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		logger.Warn("Method %s not allowed for %s", req.Method, req.URL.Path)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorize(w, req, config.ScopeSync, config.ScopeReadOnly) {
+		return
+	}
+
+	job, ok := syncJobs.Get(strings.TrimPrefix(req.URL.Path, "/jobs/"))
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	writeJson(w, http.StatusOK, job)
+}
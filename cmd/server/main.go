@@ -1,32 +1,131 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/internal/dedupe"
+	"github.com/utkuufuk/entrello/internal/jobs"
 	"github.com/utkuufuk/entrello/internal/logger"
+	"github.com/utkuufuk/entrello/internal/outbox"
 	"github.com/utkuufuk/entrello/internal/services"
 	"github.com/utkuufuk/entrello/pkg/trello"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	outboxInterval = 5 * time.Second
+	outboxBackoff  = 30 * time.Second
+	maxJobs        = 100
 )
 
 var client trello.Client
+var box *outbox.Outbox
+var processedActions *dedupe.Set
+
+// done is closed upon shutdown to stop the background loops, which are tracked by background
+var done = make(chan struct{})
+var background sync.WaitGroup
+
+var syncJobs = jobs.New(maxJobs)
+
+// member holds the ID of the Trello member that owns the API token, which is used for telling
+// apart the cards created by entrello from the ones created by humans
+var member struct {
+	sync.Mutex
+	id string
+}
 
 func main() {
-	client = trello.NewClient(config.Trello{
-		ApiKey:   config.ServerCfg.TrelloApiKey,
-		ApiToken: config.ServerCfg.TrelloApiToken,
-		BoardId:  config.ServerCfg.TrelloBoardId,
-	})
+	client = trello.NewClient(config.ServerCfg.Trello())
+
+	for _, service := range config.ServerCfg.Services {
+		for _, event := range service.Events {
+			if !slices.Contains(trello.EventTypes, event) {
+				logger.Warn("Unknown event '%s' for service %s", event, service.Endpoint)
+			}
+		}
+	}
+
+	var err error
+	if _, err = getMemberId(context.Background()); err != nil {
+		logger.Warn("Could not fetch Trello member ID, card creation events will be rejected until it's fetched: %v", err)
+	}
+
+	if box, err = outbox.New(
+		config.ServerCfg.OutboxDir,
+		config.ServerCfg.OutboxMaxAttempts,
+		outboxBackoff,
+	); err != nil {
+		logger.Error("Could not create outbox: %v", err)
+		return
+	}
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		box.Run(outboxInterval, deliver, done, func(err error) {
+			logger.Error("Could not process outbox: %v", err)
+		})
+	}()
+
+	if len(config.ServerCfg.ApiKeys) == 0 && (config.ServerCfg.Username == "" || config.ServerCfg.Password == "") {
+		logger.Warn("Neither API keys nor basic auth credentials are configured, all API requests will be rejected")
+	}
+
+	if processedActions, err = dedupe.New(
+		config.MaxProcessedActions,
+		config.ServerCfg.ProcessedActionsFile,
+	); err != nil {
+		logger.Error("Could not load processed Trello actions: %v", err)
+		return
+	}
 
 	http.HandleFunc("/", handlePollRequest)
 	http.HandleFunc("/trello-webhook", handleTrelloWebhookRequest)
+	http.HandleFunc("/dead-letters", handleDeadLettersRequest)
+	http.HandleFunc("/dead-letters/", handleReplayRequest)
+	http.HandleFunc("/status", handleStatusRequest)
+	http.HandleFunc("/sync/", handleSyncRequest)
+	http.HandleFunc("/services/", handleServiceSyncRequest)
+	http.HandleFunc("/jobs/", handleJobRequest)
+	http.HandleFunc("/healthz", handleHealthRequest)
+	http.HandleFunc("/readyz", handleReadinessRequest)
+	http.HandleFunc("/metrics", handleMetricsRequest)
+	http.HandleFunc("/dashboard", handleDashboardRequest)
+	http.HandleFunc("/dashboard/sync", handleDashboardSyncRequest)
 
-	if err := http.ListenAndServe(fmt.Sprintf(":%s", config.ServerCfg.Port), nil); err != nil {
+	// listen before reconciling the webhook, since Trello verifies the callback URL upon creation
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%s", config.ServerCfg.Port))
+	if err != nil {
 		logger.Error("Could not start server: %v", err)
+		return
+	}
+
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		reconcileWebhook(config.ServerCfg.WebhookReconcileInterval, done)
+	}()
+
+	// read the config file through readSyncConfig, so that it falls back to the Trello credentials
+	// of the server just like the sync requests do, where the only error is a missing config file
+	if syncCfg, err := readSyncConfig(""); err == nil {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			runScheduledSync(syncCfg, done)
+		}()
 	}
+
+	serve(listener)
 }
 
 func handlePollRequest(w http.ResponseWriter, req *http.Request) {
@@ -36,20 +135,7 @@ func handlePollRequest(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	user, pwd, ok := req.BasicAuth()
-	if !ok {
-		logger.Warn("Could not parse basic auth.")
-		w.WriteHeader(http.StatusUnauthorized)
-		return
-	}
-	if user != config.ServerCfg.Username {
-		logger.Warn("Invalid user name: %s", user)
-		w.WriteHeader(http.StatusUnauthorized)
-		return
-	}
-	if pwd != config.ServerCfg.Password {
-		logger.Warn("Invalid password: %s", pwd)
-		w.WriteHeader(http.StatusUnauthorized)
+	if !authorize(w, req, config.ScopeSync) {
 		return
 	}
 
@@ -67,13 +153,24 @@ func handlePollRequest(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if err = services.Poll(cfg); err != nil {
+	if isAsync(req) {
+		startSyncJob(w, cfg)
+		return
+	}
+
+	reports, err := services.Poll(req.Context(), cfg)
+	if errors.Is(err, services.ErrSyncInProgress) {
+		logger.Warn("Rejecting sync request: %v", err)
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if err != nil {
 		logger.Error(err.Error())
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	writeJson(w, http.StatusOK, reports)
 }
 
 func handleTrelloWebhookRequest(w http.ResponseWriter, req *http.Request) {
@@ -120,24 +217,140 @@ func handleTrelloWebhookRequest(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	archivedCardId := trello.ParseArchivedCardId(wrb)
-	if archivedCardId == "" {
+	event, ok := trello.ParseEvent(wrb)
+	if !ok {
 		w.WriteHeader(http.StatusAccepted)
 		return
 	}
 
-	archivedCard, err := client.GetCard(archivedCardId)
+	outcome := webhookFailed
+	defer func() { webhookEventsTotal.Inc(event.Type, outcome) }()
+
+	if event.Type == trello.EventCardCreated {
+		// fail closed so that the cards created by entrello never trigger notifications, where Trello
+		// retries the event later on
+		id, err := getMemberId(req.Context())
+		if err != nil {
+			logger.Error("Could not fetch Trello member ID: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if event.ActorId == id {
+			outcome = webhookIgnored
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+	}
+
+	if event.ActionId != "" {
+		claimed, err := processedActions.Claim(event.ActionId)
+		if err != nil {
+			logger.Warn("Could not persist processed Trello action %s: %v", event.ActionId, err)
+		}
+		if !claimed {
+			logger.Info("Ignoring duplicate Trello action %s", event.ActionId)
+			outcome = webhookDuplicate
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+
+	card, err := client.WithContext(req.Context()).GetCard(event.CardId)
 	if err != nil {
-		logger.Error("Could not fetch archived Trello card: %v", err)
+		logger.Error("Could not fetch Trello card: %v", err)
+		releaseAction(event.ActionId)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	if err = services.Notify(archivedCard, config.ServerCfg.Services); err != nil {
-		logger.Error("Could not notify service(s) with the archived card data: %v", err)
+	// skip the services that have already been notified of the action before a retry
+	pending := make([]config.Service, 0, len(config.ServerCfg.Services))
+	for _, service := range config.ServerCfg.Services {
+		if event.ActionId == "" || !processedActions.Contains(notificationId(event.ActionId, service)) {
+			pending = append(pending, service)
+		}
+	}
+
+	failed := false
+	queued := make([]config.Service, 0)
+	for _, result := range services.Notify(req.Context(), card, event, pending, box) {
+		if result.Err != nil {
+			logger.Error(
+				"Could not notify service %s of the '%s' event: %v",
+				result.Service.Endpoint,
+				event.Type,
+				result.Err,
+			)
+			failed = true
+			continue
+		}
+		logger.Info("Queued '%s' event notification for service %s", event.Type, result.Service.Endpoint)
+		queued = append(queued, result.Service)
+	}
+
+	if failed {
+		// only the services that have failed are notified when Trello retries the action
+		rememberNotified(event.ActionId, queued)
+		releaseAction(event.ActionId)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	outcome = webhookProcessed
 	w.WriteHeader(http.StatusOK)
 }
+
+// getMemberId returns the ID of the Trello member that owns the API token, fetching it unless
+// it has already been fetched
+func getMemberId(ctx context.Context) (string, error) {
+	member.Lock()
+	defer member.Unlock()
+
+	if member.id != "" {
+		return member.id, nil
+	}
+
+	id, err := client.WithContext(ctx).GetMemberId()
+	if err != nil {
+		return "", err
+	}
+	member.id = id
+	return id, nil
+}
+
+// releaseAction forgets the given Trello action ID so that the action can be processed again
+// when Trello retries the webhook delivery
+func releaseAction(actionId string) {
+	if actionId == "" {
+		return
+	}
+	if err := processedActions.Release(actionId); err != nil {
+		logger.Warn("Could not persist processed Trello action %s: %v", actionId, err)
+	}
+}
+
+// rememberNotified remembers that the given services have been notified of the given Trello action
+func rememberNotified(actionId string, services []config.Service) {
+	if actionId == "" {
+		return
+	}
+	for _, service := range services {
+		if _, err := processedActions.Claim(notificationId(actionId, service)); err != nil {
+			logger.Warn("Could not persist processed Trello action %s: %v", actionId, err)
+		}
+	}
+}
+
+// notificationId identifies the notification of a single service of the given Trello action
+func notificationId(actionId string, service config.Service) string {
+	return actionId + "/" + service.Key()
+}
+
+// writeJson responds with the given status code and the JSON encoding of the given value
+func writeJson(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warn("Could not write response body: %v", err)
+	}
+}
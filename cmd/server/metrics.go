@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/internal/logger"
+	"github.com/utkuufuk/entrello/internal/metrics"
+)
+
+const (
+	webhookProcessed = "processed"
+	webhookDuplicate = "duplicate"
+	webhookIgnored   = "ignored"
+	webhookFailed    = "failed"
+)
+
+// webhookEventsTotal counts every Trello webhook event received, including the duplicates that are
+// not processed again
+var webhookEventsTotal = metrics.NewCounter(
+	"entrello_webhook_events_total",
+	"Number of Trello webhook events received, by type and outcome.",
+	"type", "outcome",
+)
+
+// handleMetricsRequest responds with the metrics in the Prometheus text format
+func handleMetricsRequest(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		logger.Warn("Method %s not allowed for %s", req.Method, req.URL.Path)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorize(w, req, config.ScopeReadOnly) {
+		return
+	}
+
+	w.Header().Set("Content-Type", metrics.ContentType)
+	if err := metrics.Default.Write(w); err != nil {
+		logger.Warn("Could not write metrics: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/internal/logger"
+	"github.com/utkuufuk/entrello/internal/outbox"
+	"github.com/utkuufuk/entrello/internal/services"
+)
+
+// deliver delivers an outbox message to its service as currently configured, and logs the outcome
+func deliver(msg outbox.Message) error {
+	service, ok := config.FindService(config.ServerCfg.Services, msg.ServiceKey)
+	if !ok {
+		err := fmt.Errorf("service %s is not configured", msg.ServiceKey)
+		logger.Warn("Could not deliver '%s' event notification %s: %v", msg.Event, msg.Id, err)
+		return err
+	}
+
+	if err := services.Deliver(workCtx, msg, service, client); err != nil {
+		logger.Warn(
+			"Could not deliver '%s' event notification %s to service %s (attempt %d): %v",
+			msg.Event,
+			msg.Id,
+			msg.ServiceKey,
+			msg.Attempts+1,
+			err,
+		)
+		return err
+	}
+
+	logger.Info("Delivered '%s' event notification %s to service %s", msg.Event, msg.Id, msg.ServiceKey)
+	return nil
+}
+
+// handleDeadLettersRequest lists the dead letters in the outbox
+func handleDeadLettersRequest(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		logger.Warn("Method %s not allowed for %s", req.Method, req.URL.Path)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorize(w, req, config.ScopeReadOnly) {
+		return
+	}
+
+	messages, err := box.DeadLetters()
+	if err != nil {
+		logger.Error("Could not list dead letters: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeJson(w, http.StatusOK, messages)
+}
+
+// handleReplayRequest moves the dead letter given by the path /dead-letters/{id}/replay
+// back to the pending messages in the outbox
+func handleReplayRequest(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		logger.Warn("Method %s not allowed for %s", req.Method, req.URL.Path)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorize(w, req, config.ScopeAdmin) {
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, "/dead-letters/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] != "replay" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if err := box.Replay(parts[0]); err != nil {
+		if errors.Is(err, outbox.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		logger.Error("Could not replay dead letter %s: %v", parts[0], err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Replaying dead letter %s", parts[0])
+	w.WriteHeader(http.StatusAccepted)
+}
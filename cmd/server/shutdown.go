@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/internal/logger"
+)
+
+// workCtx is the parent context of all the work done by the server, including the requests, which is
+// cancelled if the work in progress doesn't finish within the shutdown timeout
+var workCtx, cancelWork = context.WithCancel(context.Background())
+
+// serve serves HTTP requests on the given listener until SIGTERM or SIGINT is received, and then shuts
+// down gracefully: it stops accepting new requests and stops the background loops, then waits for the
+// in-flight requests, sync jobs, polls and notification deliveries to finish within the shutdown timeout,
+// after which the remaining work is cancelled
+func serve(listener net.Listener) {
+	server := &http.Server{
+		BaseContext: func(net.Listener) context.Context { return workCtx },
+	}
+
+	signals, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-errs:
+		logger.Error("Could not start server: %v", err)
+		return
+	case <-signals.Done():
+	}
+
+	logger.Info("Shutting down, waiting up to %v for the work in progress to finish", config.ServerCfg.ShutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), config.ServerCfg.ShutdownTimeout)
+	defer cancel()
+
+	close(done)
+	if err := server.Shutdown(ctx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		logger.Error("Could not shut down server: %v", err)
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		background.Wait()
+		syncJobs.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		logger.Info("Shut down gracefully")
+	case <-ctx.Done():
+		logger.Warn("Shutdown timeout exceeded, cancelling the work in progress")
+		cancelWork()
+		server.Close()
+
+		// give the cancelled work a moment to wind down and log its outcome
+		select {
+		case <-finished:
+		case <-time.After(time.Second):
+		}
+	}
+}
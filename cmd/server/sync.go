@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/internal/logger"
+	"github.com/utkuufuk/entrello/internal/services"
+)
+
+// maxOverridesSize is the maximum size of the sync overrides in a request body
+const maxOverridesSize = 1 << 16
+
+// runScheduledSync polls the services of the given configuration at the start of each minute,
+// where each service decides whether it should be polled based on its own period, just like
+// the runner does when it's run as a cron job every minute. Returns once the done channel is closed,
+// after the sync in progress, if any, has finished.
+func runScheduledSync(cfg config.RunnerConfig, done <-chan struct{}) {
+	for {
+		now := time.Now()
+		timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+		select {
+		case <-done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		_, err := services.Poll(workCtx, cfg)
+		if errors.Is(err, services.ErrSyncInProgress) {
+			logger.Warn("Skipping scheduled sync: %v", err)
+			continue
+		}
+		if err != nil {
+			logger.Error("Scheduled sync failed: %v", err)
+		}
+	}
+}
+
+// handleSyncRequest synchronizes the services of the stored sync profile given in the path,
+// i.e. POST /sync/{profile}, where the optional request body may contain overrides such as
+// a subset of the services or dry-run mode
+func handleSyncRequest(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		logger.Warn("Method %s not allowed for %s", req.Method, req.URL.Path)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorize(w, req, config.ScopeSync) {
+		return
+	}
+
+	name := strings.TrimPrefix(req.URL.Path, "/sync/")
+	if name == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	cfg, err := readSyncConfig(name)
+	if errors.Is(err, config.ErrProfileNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Error("Could not read sync profile '%s': %v", name, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var overrides config.SyncOverrides
+	err = json.NewDecoder(io.LimitReader(req.Body, maxOverridesSize)).Decode(&overrides)
+	if err != nil && err != io.EOF {
+		logger.Warn("Invalid sync overrides: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if cfg, err = cfg.ApplyOverrides(overrides); err != nil {
+		logger.Warn("Invalid sync overrides for profile '%s': %v", name, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if isAsync(req) {
+		startSyncJob(w, cfg)
+		return
+	}
+
+	reports, err := services.Poll(req.Context(), cfg)
+	if errors.Is(err, services.ErrSyncInProgress) {
+		logger.Warn("Rejecting sync request for profile '%s': %v", name, err)
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if err != nil {
+		logger.Error("Sync failed for profile '%s': %v", name, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeJson(w, http.StatusOK, reports)
+}
+
+// handleServiceSyncRequest synchronizes a single service regardless of its period, i.e.
+// POST /services/{name}/sync, and responds with the report of the service. The service is looked up
+// in the sync profile given by the "profile" query parameter, or in the config file of the server.
+func handleServiceSyncRequest(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		logger.Warn("Method %s not allowed for %s", req.Method, req.URL.Path)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorize(w, req, config.ScopeSync) {
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, "/services/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] != "sync" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	cfg, err := readSyncConfig(req.URL.Query().Get("profile"))
+	if errors.Is(err, config.ErrProfileNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Error("Could not read sync configuration: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	report, err := services.PollService(req.Context(), cfg, parts[0])
+	if errors.Is(err, services.ErrServiceNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, services.ErrSyncInProgress) {
+		logger.Warn("Rejecting sync request for service '%s': %v", parts[0], err)
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if err != nil {
+		logger.Error("Sync failed for service '%s': %v", parts[0], err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if len(report.Errors) > 0 {
+		writeJson(w, http.StatusBadGateway, report)
+		return
+	}
+	writeJson(w, http.StatusOK, report)
+}
+
+// readSyncConfig reads the sync profile with the given name, or returns the configuration
+// of the config file of the server if the name is empty
+func readSyncConfig(profile string) (cfg config.RunnerConfig, err error) {
+	if profile == "" {
+		if config.ServerCfg.Sync == nil {
+			return cfg, config.ErrProfileNotFound
+		}
+		cfg = *config.ServerCfg.Sync
+	} else if cfg, err = config.ReadSyncProfile(config.ServerCfg.ProfilesDir, profile); err != nil {
+		return cfg, err
+	}
+
+	// profiles may omit any of the Trello credentials in favor of the ones of the server
+	cfg.Trello = cfg.Trello.WithFallback(config.ServerCfg.Trello())
+	return cfg, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/internal/logger"
+	"github.com/utkuufuk/entrello/pkg/trello"
+)
+
+// webhookStatus represents the state of the Trello webhook as of the latest reconciliation
+type webhookStatus struct {
+	Id          string    `json:"id"`
+	CallbackUrl string    `json:"callback_url"`
+	Active      bool      `json:"active"`
+	LastChecked time.Time `json:"last_checked"`
+	LastAction  string    `json:"last_action"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+var webhookState struct {
+	sync.Mutex
+	status webhookStatus
+}
+
+// reconcileWebhook makes sure that an active webhook exists for the Trello board with the configured
+// callback URL on startup, and periodically afterwards unless the interval is zero, until the done
+// channel is closed
+func reconcileWebhook(interval time.Duration, done <-chan struct{}) {
+	if config.ServerCfg.TrelloWebhookCallbackUrl == "" || config.ServerCfg.TrelloBoardId == "" {
+		logger.Warn("Trello webhook callback URL or board ID is missing, skipping webhook reconciliation")
+		return
+	}
+
+	ensureWebhook()
+	if interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			ensureWebhook()
+		}
+	}
+}
+
+func ensureWebhook() {
+	webhook, action, err := client.EnsureWebhook(
+		config.ServerCfg.TrelloWebhookCallbackUrl,
+		config.DefaultWebhookDescription,
+	)
+
+	webhookState.Lock()
+	defer webhookState.Unlock()
+
+	status := &webhookState.status
+	status.CallbackUrl = config.ServerCfg.TrelloWebhookCallbackUrl
+	status.LastChecked = time.Now().UTC()
+	status.LastAction = action
+
+	if err != nil {
+		logger.Error("Could not reconcile Trello webhook: %v", err)
+		status.LastError = err.Error()
+		return
+	}
+
+	status.Id = webhook.ID
+	status.Active = webhook.Active
+	status.LastError = ""
+	if action != trello.WebhookActionNone {
+		logger.Info("Trello webhook %s: %s", action, webhook.ID)
+	}
+}
+
+// handleStatusRequest responds with the status of the Trello webhook
+func handleStatusRequest(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		logger.Warn("Method %s not allowed for %s", req.Method, req.URL.Path)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorize(w, req, config.ScopeReadOnly) {
+		return
+	}
+
+	webhookState.Lock()
+	status := webhookState.status
+	webhookState.Unlock()
+
+	writeJson(w, http.StatusOK, struct {
+		Webhook webhookStatus `json:"webhook"`
+	}{status})
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+
+	"github.com/utkuufuk/entrello/internal/config"
+	"golang.org/x/exp/slices"
+)
+
+// keyBytes is the number of random bytes in a generated API key
+const keyBytes = 32
+
+// Authenticate returns the API key that matches the given secret, if any. The hash of the secret is
+// compared with the hash of every key in constant time, so that the response time reveals nothing
+// about the keys.
+func Authenticate(keys []config.ApiKey, secret string) (config.ApiKey, bool) {
+	hash := Hash(secret)
+
+	var match config.ApiKey
+	found := false
+	for _, key := range keys {
+		expected, err := hex.DecodeString(key.Hash)
+		if err != nil {
+			continue
+		}
+		if subtle.ConstantTimeCompare(hash, expected) == 1 {
+			match = key
+			found = true
+		}
+	}
+	return match, found
+}
+
+// HasScope checks whether the key has any of the given scopes, where the admin scope grants all scopes
+func HasScope(key config.ApiKey, scopes ...string) bool {
+	if slices.Contains(key.Scopes, config.ScopeAdmin) {
+		return true
+	}
+	for _, scope := range scopes {
+		if slices.Contains(key.Scopes, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// Hash returns the SHA-256 hash of the given secret, which is sufficient for API keys since they are
+// long random strings, unlike passwords
+func Hash(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+// GenerateKey generates a new random API key, and returns it along with its hex-encoded hash
+func GenerateKey() (secret, hash string, err error) {
+	b := make([]byte, keyBytes)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	secret = hex.EncodeToString(b)
+	return secret, hex.EncodeToString(Hash(secret)), nil
+}
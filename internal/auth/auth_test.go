@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/utkuufuk/entrello/internal/config"
+)
+
+func TestAuthenticate(t *testing.T) {
+	keys := []config.ApiKey{
+		{Name: "cron", Hash: hex.EncodeToString(Hash("s3cret")), Scopes: []string{config.ScopeSync}},
+		{Name: "invalid", Hash: "not-hex", Scopes: []string{config.ScopeAdmin}},
+		{Name: "grafana", Hash: hex.EncodeToString(Hash("r3ad")), Scopes: []string{config.ScopeReadOnly}},
+	}
+
+	tt := []struct {
+		name   string
+		secret string
+		key    string
+		ok     bool
+	}{
+		{name: "first key", secret: "s3cret", key: "cron", ok: true},
+		{name: "last key", secret: "r3ad", key: "grafana", ok: true},
+		{name: "unknown secret", secret: "s3cre", ok: false},
+		{name: "empty secret", secret: "", ok: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			key, ok := Authenticate(keys, tc.secret)
+			if ok != tc.ok {
+				t.Fatalf("wanted ok: %t, got %t", tc.ok, ok)
+			}
+			if key.Name != tc.key {
+				t.Errorf("wanted key '%s', got '%s'", tc.key, key.Name)
+			}
+		})
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	tt := []struct {
+		name   string
+		scopes []string
+		want   []string
+		ok     bool
+	}{
+		{name: "matching scope", scopes: []string{config.ScopeSync}, want: []string{config.ScopeSync}, ok: true},
+		{name: "any of the scopes", scopes: []string{config.ScopeReadOnly}, want: []string{config.ScopeSync, config.ScopeReadOnly}, ok: true},
+		{name: "missing scope", scopes: []string{config.ScopeReadOnly}, want: []string{config.ScopeSync}, ok: false},
+		{name: "admin grants all scopes", scopes: []string{config.ScopeAdmin}, want: []string{config.ScopeSync}, ok: true},
+		{name: "no scopes", scopes: []string{}, want: []string{config.ScopeReadOnly}, ok: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if ok := HasScope(config.ApiKey{Scopes: tc.scopes}, tc.want...); ok != tc.ok {
+				t.Errorf("wanted %t, got %t", tc.ok, ok)
+			}
+		})
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	secret, hash, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+
+	if _, ok := Authenticate([]config.ApiKey{{Name: "new", Hash: hash}}, secret); !ok {
+		t.Errorf("generated key does not match its hash")
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2022, 4, 2, 9, 0, 0, 0, time.UTC)
+	l := NewLimiter(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	l.Fail("a")
+	if !l.Allow("a") {
+		t.Errorf("wanted client a to be allowed after one failure")
+	}
+
+	l.Fail("a")
+	if l.Allow("a") {
+		t.Errorf("wanted client a to be blocked after two failures")
+	}
+	if !l.Allow("b") {
+		t.Errorf("wanted client b to be allowed")
+	}
+
+	now = now.Add(time.Minute)
+	if !l.Allow("a") {
+		t.Errorf("wanted client a to be allowed once the window has expired")
+	}
+}
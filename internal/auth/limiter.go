@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter keeps track of the failed authentication attempts of each client, and blocks a client
+// for the rest of the window once it has failed too many times within the window
+type Limiter struct {
+	maxFailures int
+	window      time.Duration
+	now         func() time.Time
+	mu          sync.Mutex
+	clients     map[string]*failures
+}
+
+type failures struct {
+	count int
+	start time.Time
+}
+
+// NewLimiter creates a limiter that allows at most maxFailures failed attempts per client within
+// each window
+func NewLimiter(maxFailures int, window time.Duration) *Limiter {
+	return &Limiter{
+		maxFailures: maxFailures,
+		window:      window,
+		now:         time.Now,
+		clients:     make(map[string]*failures),
+	}
+}
+
+// Allow checks whether the given client is allowed to attempt authentication
+func (l *Limiter) Allow(client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, ok := l.clients[client]
+	if !ok {
+		return true
+	}
+	if l.now().Sub(f.start) >= l.window {
+		delete(l.clients, client)
+		return true
+	}
+	return f.count < l.maxFailures
+}
+
+// Fail records a failed authentication attempt of the given client
+func (l *Limiter) Fail(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.evict(now)
+
+	f, ok := l.clients[client]
+	if !ok || now.Sub(f.start) >= l.window {
+		l.clients[client] = &failures{count: 1, start: now}
+		return
+	}
+	f.count++
+}
+
+// evict forgets the clients whose windows have expired, so that the memory usage stays bounded
+func (l *Limiter) evict(now time.Time) {
+	for client, f := range l.clients {
+		if now.Sub(f.start) >= l.window {
+			delete(l.clients, client)
+		}
+	}
+}
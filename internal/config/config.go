@@ -2,8 +2,14 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
 )
 
 type Period struct {
@@ -11,14 +17,116 @@ type Period struct {
 	Interval int    `json:"interval"`
 }
 
+// Mapping describes how the items of an arbitrary JSON API response are mapped to Trello cards.
+// Each field is either a dot-separated selector (e.g. "fields.title") or a Go template
+// (e.g. "{{.title}} ({{.id}})") that is evaluated against a single item. The templates are parsed
+// once upon decoding, where referring to a field that an item lacks is an error.
+type Mapping struct {
+	Items       string `json:"items"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Due         string `json:"due"`
+	DueLayout   string `json:"due_layout"`
+
+	templates map[string]*template.Template
+}
+
+// UnmarshalJSON decodes the mapping and parses its templates
+func (m *Mapping) UnmarshalJSON(data []byte) error {
+	type mapping Mapping
+	var raw mapping
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*m = Mapping(raw)
+	return m.ParseTemplates()
+}
+
+// ParseTemplates parses the fields of the mapping that are Go templates, unless already parsed
+func (m *Mapping) ParseTemplates() error {
+	if m.templates != nil {
+		return nil
+	}
+
+	templates := make(map[string]*template.Template)
+	for _, field := range []struct{ name, expr string }{
+		{"name", m.Name},
+		{"description", m.Description},
+		{"due", m.Due},
+	} {
+		if !isTemplate(field.expr) {
+			continue
+		}
+		tmpl, err := template.New(field.name).Option("missingkey=error").Parse(field.expr)
+		if err != nil {
+			return fmt.Errorf("invalid %s template in mapping: %w", field.name, err)
+		}
+		templates[field.expr] = tmpl
+	}
+	m.templates = templates
+	return nil
+}
+
+// Template returns the parsed template of the given mapping expression, if it is a template
+func (m Mapping) Template(expr string) (*template.Template, bool) {
+	tmpl, ok := m.templates[expr]
+	return tmpl, ok
+}
+
+// isTemplate checks whether the given mapping expression is a Go template rather than a selector
+func isTemplate(expr string) bool {
+	return strings.Contains(expr, "{{")
+}
+
+// Http contains the settings of the HTTP client used for communicating with a service. Timeout and
+// backoff are Go duration strings such as "10s" or "500ms", and the certificates are PEM file paths.
+type Http struct {
+	Timeout    string `json:"timeout"`
+	Retries    int    `json:"retries"`
+	Backoff    string `json:"backoff"`
+	Proxy      string `json:"proxy"`
+	CaCert     string `json:"ca_cert"`
+	ClientCert string `json:"client_cert"`
+	ClientKey  string `json:"client_key"`
+}
+
+// Incremental contains the settings of incremental polling, where the cursor from the latest
+// successful poll is sent either as a query parameter or in a request header
+type Incremental struct {
+	Enabled bool   `json:"enabled"`
+	Param   string `json:"param"`
+	Header  string `json:"header"`
+}
+
+// Route describes which cards trigger automation events for a service, where a card must match all
+// the non-empty criteria of a route, i.e. label, list and board IDs, and a regex for the card name
+type Route struct {
+	Label string `json:"label_id"`
+	List  string `json:"list_id"`
+	Board string `json:"board_id"`
+	Name  string `json:"name_regex"`
+}
+
 type Service struct {
-	Name     string `json:"name"`
-	Endpoint string `json:"endpoint"`
-	Secret   string `json:"secret"`
-	Strict   bool   `json:"strict"`
-	Label    string `json:"label_id"`
-	List     string `json:"list_id"`
-	Period   Period `json:"period"`
+	Name         string      `json:"name"`
+	Type         string      `json:"type"`
+	Endpoint     string      `json:"endpoint"`
+	Secret       string      `json:"secret"`
+	Strict       bool        `json:"strict"`
+	StrictSchema bool        `json:"strict_schema"`
+	Label        string      `json:"label_id"`
+	List         string      `json:"list_id"`
+	Period       Period      `json:"period"`
+	Mapping      Mapping     `json:"mapping"`
+	MaxBodySize  int64       `json:"max_body_size"`
+	MaxItems     int         `json:"max_items"`
+	Http         Http        `json:"http"`
+	Incremental  Incremental `json:"incremental"`
+	Events       []string    `json:"events"`
+	Envelope     bool        `json:"envelope"`
+	Routes       []Route     `json:"routes"`
+	Notify       bool        `json:"notify"`
 }
 
 type Trello struct {
@@ -29,10 +137,20 @@ type Trello struct {
 
 type RunnerConfig struct {
 	TimezoneLocation string    `json:"timezone_location"`
+	StateFile        string    `json:"state_file"`
+	DryRun           bool      `json:"dry_run"`
 	Trello           Trello    `json:"trello"`
 	Services         []Service `json:"services"`
 }
 
+// ApiKey represents a named API key of the server, where only the hex-encoded SHA-256 hash of the key
+// is stored along with the scopes that the key grants access to
+type ApiKey struct {
+	Name   string
+	Hash   string
+	Scopes []string
+}
+
 type ServerConfig struct {
 	Port                     string
 	Username                 string
@@ -43,6 +161,23 @@ type ServerConfig struct {
 	TrelloBoardId            string
 	TrelloSecret             string
 	TrelloWebhookCallbackUrl string
+	OutboxDir                string
+	OutboxMaxAttempts        int
+	ProcessedActionsFile     string
+	WebhookReconcileInterval time.Duration
+	Sync                     *RunnerConfig
+	ProfilesDir              string
+	ReadinessCheckServices   bool
+	ApiKeys                  []ApiKey
+	TrustProxy               bool
+	ShutdownTimeout          time.Duration
+}
+
+// SyncOverrides contains the settings that a sync request may override in a stored sync profile,
+// where the services are selected by name and all the services are synchronized if none is given
+type SyncOverrides struct {
+	Services []string `json:"services"`
+	DryRun   bool     `json:"dry_run"`
 }
 
 const (
@@ -52,8 +187,39 @@ const (
 	PeriodTypeMinute  = "minute"
 )
 
+const (
+	ScopeSync     = "sync"
+	ScopeAdmin    = "admin"
+	ScopeReadOnly = "read-only"
+)
+
+const (
+	ServiceTypeDefault = ""
+	ServiceTypeJsonApi = "json_api"
+)
+
+const (
+	DefaultMaxBodySize  = 10 << 20
+	DefaultMaxItems     = 1000
+	DefaultTimeout      = 30 * time.Second
+	DefaultBackoff      = 500 * time.Millisecond
+	DefaultCursorParam  = "since"
+	DefaultStateFile    = "entrello-state.json"
+	DefaultOutboxDir    = "outbox"
+	DefaultProfilesDir  = "profiles"
+	DefaultMaxAttempts  = 10
+	MaxProcessedActions = 1000
+
+	DefaultWebhookDescription       = "entrello"
+	DefaultWebhookReconcileInterval = time.Hour
+	DefaultShutdownTimeout          = 25 * time.Second
+	ReadinessCacheTtl               = time.Minute
+	ReadinessTimeout                = 5 * time.Second
+)
+
 var ServerCfg ServerConfig
 
+// ReadRunnerConfig reads the configuration from the given JSON file
 func ReadRunnerConfig(fileName string) (cfg RunnerConfig, err error) {
 	f, err := os.Open(fileName)
 	if err != nil {
@@ -65,3 +231,120 @@ func ReadRunnerConfig(fileName string) (cfg RunnerConfig, err error) {
 	err = decoder.Decode(&cfg)
 	return cfg, err
 }
+
+// ErrProfileNotFound is returned when a sync profile with the given name does not exist
+var ErrProfileNotFound = errors.New("sync profile not found")
+
+var profileName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// ReadSyncProfile reads the sync profile with the given name, which is a runner configuration file
+// called "<name>.json" in the given directory
+func ReadSyncProfile(dir, name string) (cfg RunnerConfig, err error) {
+	if !profileName.MatchString(name) {
+		return cfg, ErrProfileNotFound
+	}
+
+	path := filepath.Join(dir, name+".json")
+	if _, err = os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return cfg, ErrProfileNotFound
+	}
+	return ReadRunnerConfig(path)
+}
+
+// ApplyOverrides returns a copy of the configuration with the given overrides applied,
+// failing if any of the selected services does not exist in the configuration
+func (cfg RunnerConfig) ApplyOverrides(overrides SyncOverrides) (RunnerConfig, error) {
+	cfg.DryRun = cfg.DryRun || overrides.DryRun
+	if len(overrides.Services) == 0 {
+		return cfg, nil
+	}
+
+	services := make([]Service, 0, len(overrides.Services))
+	for _, name := range overrides.Services {
+		found := false
+		for _, service := range cfg.Services {
+			if service.Name == name {
+				services = append(services, service)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return cfg, fmt.Errorf("unknown service '%s'", name)
+		}
+	}
+	cfg.Services = services
+	return cfg, nil
+}
+
+// NotificationServices returns the services of the given configuration that have opted into automation
+// notifications, after validating their events and routes
+func NotificationServices(cfg RunnerConfig) ([]Service, error) {
+	services := make([]Service, 0)
+	for _, service := range cfg.Services {
+		if !service.Notify {
+			continue
+		}
+
+		if service.Endpoint == "" {
+			return nil, fmt.Errorf("service '%s' has no endpoint", service.Name)
+		}
+
+		if service.Label == "" && len(service.Routes) == 0 {
+			return nil, fmt.Errorf("service '%s' without routes must have a label", service.Name)
+		}
+
+		for _, event := range service.Events {
+			if !eventName.MatchString(event) {
+				return nil, fmt.Errorf("invalid event '%s' for service '%s'", event, service.Name)
+			}
+		}
+
+		for i, route := range service.Routes {
+			if err := ValidateRoute(route); err != nil {
+				return nil, fmt.Errorf("invalid route %d for service '%s': %w", i, service.Name, err)
+			}
+		}
+		services = append(services, service)
+	}
+	return services, nil
+}
+
+// Key identifies the service without its secret, i.e. by its name if it has one, or by its label and
+// endpoint otherwise, so that it can be referred to from persisted data such as the outbox
+func (s Service) Key() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Label + "@" + s.Endpoint
+}
+
+// FindService returns the service with the given key among the given services
+func FindService(services []Service, key string) (Service, bool) {
+	for _, service := range services {
+		if service.Key() == key {
+			return service, true
+		}
+	}
+	return Service{}, false
+}
+
+// WithFallback returns a copy of the Trello credentials where each missing field is taken from
+// the given fallback credentials
+func (t Trello) WithFallback(fallback Trello) Trello {
+	if t.ApiKey == "" {
+		t.ApiKey = fallback.ApiKey
+	}
+	if t.ApiToken == "" {
+		t.ApiToken = fallback.ApiToken
+	}
+	if t.BoardId == "" {
+		t.BoardId = fallback.BoardId
+	}
+	return t
+}
+
+// Trello returns the Trello credentials of the server
+func (cfg ServerConfig) Trello() Trello {
+	return Trello{ApiKey: cfg.TrelloApiKey, ApiToken: cfg.TrelloApiToken, BoardId: cfg.TrelloBoardId}
+}
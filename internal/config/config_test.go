@@ -0,0 +1,256 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNotificationServices(t *testing.T) {
+	tt := []struct {
+		name      string
+		services  []Service
+		isValid   bool
+		endpoints []string
+	}{
+		{
+			name: "only services with notifications enabled",
+			services: []Service{
+				{Name: "a", Endpoint: "http://a", Label: "a", Notify: true},
+				{Name: "b", Endpoint: "http://b", Label: "b"},
+				{Name: "c", Endpoint: "http://c", Routes: []Route{{List: "c"}}, Notify: true},
+			},
+			isValid:   true,
+			endpoints: []string{"http://a", "http://c"},
+		},
+		{
+			name:     "service without endpoint",
+			services: []Service{{Name: "a", Label: "a", Notify: true}},
+			isValid:  false,
+		},
+		{
+			name:     "service without label and routes",
+			services: []Service{{Name: "a", Endpoint: "http://a", Notify: true}},
+			isValid:  false,
+		},
+		{
+			name: "service with invalid event",
+			services: []Service{
+				{Name: "a", Endpoint: "http://a", Label: "a", Events: []string{"Card Moved"}, Notify: true},
+			},
+			isValid: false,
+		},
+		{
+			name: "service with invalid route",
+			services: []Service{
+				{Name: "a", Endpoint: "http://a", Routes: []Route{{Name: "("}}, Notify: true},
+			},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			services, err := NotificationServices(RunnerConfig{Services: tc.services})
+			if (err == nil) != tc.isValid {
+				t.Fatalf("wanted valid: %t, got error: %v", tc.isValid, err)
+			}
+
+			endpoints := make([]string, 0)
+			for _, service := range services {
+				endpoints = append(endpoints, service.Endpoint)
+			}
+			if tc.isValid && !reflect.DeepEqual(endpoints, tc.endpoints) {
+				t.Errorf("wanted endpoints %v, got %v", tc.endpoints, endpoints)
+			}
+		})
+	}
+}
+
+func TestApplyOverrides(t *testing.T) {
+	cfg := RunnerConfig{Services: []Service{{Name: "a"}, {Name: "b"}, {Name: "c"}}}
+
+	tt := []struct {
+		name      string
+		overrides SyncOverrides
+		isValid   bool
+		services  []string
+		dryRun    bool
+	}{
+		{
+			name:     "no overrides",
+			isValid:  true,
+			services: []string{"a", "b", "c"},
+		},
+		{
+			name:      "subset of services in dry-run mode",
+			overrides: SyncOverrides{Services: []string{"c", "a"}, DryRun: true},
+			isValid:   true,
+			services:  []string{"c", "a"},
+			dryRun:    true,
+		},
+		{
+			name:      "unknown service",
+			overrides: SyncOverrides{Services: []string{"a", "d"}},
+			isValid:   false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := cfg.ApplyOverrides(tc.overrides)
+			if (err == nil) != tc.isValid {
+				t.Fatalf("wanted valid: %t, got error: %v", tc.isValid, err)
+			}
+			if !tc.isValid {
+				return
+			}
+
+			services := make([]string, 0)
+			for _, service := range got.Services {
+				services = append(services, service.Name)
+			}
+			if !reflect.DeepEqual(services, tc.services) {
+				t.Errorf("wanted services %v, got %v", tc.services, services)
+			}
+			if got.DryRun != tc.dryRun {
+				t.Errorf("wanted dry run %t, got %t", tc.dryRun, got.DryRun)
+			}
+		})
+	}
+
+	if len(cfg.Services) != 3 {
+		t.Errorf("overrides must not modify the original configuration")
+	}
+}
+
+func TestReadSyncProfile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "daily.json"), []byte(`{"services": [{"name": "a"}]}`), 0o600); err != nil {
+		t.Fatalf("could not write profile: %v", err)
+	}
+
+	cfg, err := ReadSyncProfile(dir, "daily")
+	if err != nil {
+		t.Fatalf("could not read profile: %v", err)
+	}
+	if len(cfg.Services) != 1 || cfg.Services[0].Name != "a" {
+		t.Errorf("unexpected services: %v", cfg.Services)
+	}
+
+	for _, name := range []string{"weekly", "../daily", ""} {
+		if _, err = ReadSyncProfile(dir, name); !errors.Is(err, ErrProfileNotFound) {
+			t.Errorf("wanted ErrProfileNotFound for '%s', got %v", name, err)
+		}
+	}
+}
+
+func TestReadRunnerConfigMapping(t *testing.T) {
+	tt := []struct {
+		name    string
+		mapping string
+		err     string
+	}{
+		{
+			name:    "selectors and templates",
+			mapping: `{"name": "#{{.number}} {{.title}}", "description": "html_url"}`,
+		},
+		{
+			name:    "invalid name template",
+			mapping: `{"name": "{{.title"}`,
+			err:     "invalid name template in mapping: template: name:1: unclosed action",
+		},
+		{
+			name:    "invalid due template",
+			mapping: `{"name": "title", "due": "{{.due | nope}}"}`,
+			err:     `invalid due template in mapping: template: due:1: function "nope" not defined`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			body := `{"services": [{"type": "json_api", "mapping": ` + tc.mapping + `}]}`
+			if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+				t.Fatalf("could not write config: %v", err)
+			}
+
+			cfg, err := ReadRunnerConfig(path)
+			if err != nil || tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("expected error to be '%s', got '%v'", tc.err, err)
+				}
+				return
+			}
+
+			mapping := cfg.Services[0].Mapping
+			if _, ok := mapping.Template(mapping.Name); !ok {
+				t.Errorf("wanted the name template to be parsed")
+			}
+			if _, ok := mapping.Template(mapping.Description); ok {
+				t.Errorf("wanted the description selector not to be parsed as a template")
+			}
+		})
+	}
+}
+
+func TestFindService(t *testing.T) {
+	services := []Service{
+		{Name: "a", Endpoint: "http://a", Secret: "s"},
+		{Label: "b", Endpoint: "http://b", Secret: "s"},
+	}
+
+	tt := []struct {
+		key      string
+		found    bool
+		endpoint string
+	}{
+		{key: "a", found: true, endpoint: "http://a"},
+		{key: "b@http://b", found: true, endpoint: "http://b"},
+		{key: "b", found: false},
+		{key: "@http://a", found: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.key, func(t *testing.T) {
+			service, found := FindService(services, tc.key)
+			if found != tc.found {
+				t.Fatalf("wanted found: %t, got %t", tc.found, found)
+			}
+			if service.Endpoint != tc.endpoint {
+				t.Errorf("wanted endpoint '%s', got '%s'", tc.endpoint, service.Endpoint)
+			}
+		})
+	}
+}
+
+func TestTrelloWithFallback(t *testing.T) {
+	fallback := Trello{ApiKey: "key", ApiToken: "token", BoardId: "board"}
+	tt := []struct {
+		name   string
+		trello Trello
+		want   Trello
+	}{
+		{name: "no credentials", trello: Trello{}, want: fallback},
+		{
+			name:   "board only",
+			trello: Trello{BoardId: "other"},
+			want:   Trello{ApiKey: "key", ApiToken: "token", BoardId: "other"},
+		},
+		{
+			name:   "all credentials",
+			trello: Trello{ApiKey: "k", ApiToken: "t", BoardId: "b"},
+			want:   Trello{ApiKey: "k", ApiToken: "t", BoardId: "b"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.trello.WithFallback(fallback); got != tc.want {
+				t.Errorf("wanted %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
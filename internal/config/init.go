@@ -16,6 +16,74 @@ func init() {
 		os.Exit(1)
 	}
 
+	outboxDir := os.Getenv("OUTBOX_DIR")
+	if outboxDir == "" {
+		outboxDir = DefaultOutboxDir
+	}
+
+	outboxMaxAttempts, err := parsePositiveInt(os.Getenv("OUTBOX_MAX_ATTEMPTS"), DefaultMaxAttempts)
+	if err != nil {
+		fmt.Println("Could not parse the environment variable 'OUTBOX_MAX_ATTEMPTS':", err)
+		os.Exit(1)
+	}
+
+	profilesDir := os.Getenv("PROFILES_DIR")
+	if profilesDir == "" {
+		profilesDir = DefaultProfilesDir
+	}
+
+	reconcileInterval, err := parseDuration(
+		os.Getenv("WEBHOOK_RECONCILE_INTERVAL"),
+		DefaultWebhookReconcileInterval,
+	)
+	if err != nil {
+		fmt.Println("Could not parse the environment variable 'WEBHOOK_RECONCILE_INTERVAL':", err)
+		os.Exit(1)
+	}
+
+	shutdownTimeout, err := parseDuration(os.Getenv("SHUTDOWN_TIMEOUT"), DefaultShutdownTimeout)
+	if err != nil {
+		fmt.Println("Could not parse the environment variable 'SHUTDOWN_TIMEOUT':", err)
+		os.Exit(1)
+	}
+
+	apiKeys, err := parseApiKeys(os.Getenv("API_KEYS"))
+	if err != nil {
+		fmt.Println("Could not parse the environment variable 'API_KEYS':", err)
+		os.Exit(1)
+	}
+
+	trustProxy, err := parseBool(os.Getenv("TRUST_PROXY"), false)
+	if err != nil {
+		fmt.Println("Could not parse the environment variable 'TRUST_PROXY':", err)
+		os.Exit(1)
+	}
+
+	checkServices, err := parseBool(os.Getenv("READINESS_CHECK_SERVICES"), false)
+	if err != nil {
+		fmt.Println("Could not parse the environment variable 'READINESS_CHECK_SERVICES':", err)
+		os.Exit(1)
+	}
+
+	// an optional config file drives both the scheduled sync and the automation, in addition to 'SERVICES'
+	var sync *RunnerConfig
+	if configFile := os.Getenv("CONFIG_FILE"); configFile != "" {
+		cfg, err := ReadRunnerConfig(configFile)
+		if err != nil {
+			fmt.Println("Could not read the config file:", err)
+			os.Exit(1)
+		}
+
+		notificationServices, err := NotificationServices(cfg)
+		if err != nil {
+			fmt.Println("Invalid config file:", err)
+			os.Exit(1)
+		}
+
+		services = append(services, notificationServices...)
+		sync = &cfg
+	}
+
 	ServerCfg = ServerConfig{
 		Port:                     os.Getenv("PORT"),
 		Username:                 os.Getenv("USERNAME"),
@@ -26,5 +94,23 @@ func init() {
 		TrelloBoardId:            os.Getenv("TRELLO_BOARD_ID"),
 		TrelloSecret:             os.Getenv("TRELLO_SECRET"),
 		TrelloWebhookCallbackUrl: os.Getenv("TRELLO_WEBHOOK_CALLBACK_URL"),
+		OutboxDir:                outboxDir,
+		OutboxMaxAttempts:        outboxMaxAttempts,
+		ProcessedActionsFile:     os.Getenv("PROCESSED_ACTIONS_FILE"),
+		WebhookReconcileInterval: reconcileInterval,
+		Sync:                     sync,
+		ProfilesDir:              profilesDir,
+		ReadinessCheckServices:   checkServices,
+		ApiKeys:                  apiKeys,
+		TrustProxy:               trustProxy,
+		ShutdownTimeout:          shutdownTimeout,
+	}
+
+	// fall back to the Trello credentials of the config file
+	if sync != nil {
+		trello := ServerCfg.Trello().WithFallback(sync.Trello)
+		ServerCfg.TrelloApiKey = trello.ApiKey
+		ServerCfg.TrelloApiToken = trello.ApiToken
+		ServerCfg.TrelloBoardId = trello.BoardId
 	}
 }
@@ -3,10 +3,23 @@ package config
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var alphaNumeric = regexp.MustCompile(`^[a-zA-Z0-9]*$`)
+var eventName = regexp.MustCompile(`^[a-z_]+$`)
+var keyName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+var sha256Hex = regexp.MustCompile(`^[a-fA-F0-9]{64}$`)
+
+const (
+	// optionEnvelope opts a service into the event envelope notification format
+	optionEnvelope = "envelope"
+
+	// optionRoute prefixes a route option, e.g. "route:list=<id>;name=<regex>"
+	optionRoute = "route:"
+)
 
 func parseServices(input string) ([]Service, error) {
 	if input == "" {
@@ -17,7 +30,8 @@ func parseServices(input string) ([]Service, error) {
 	services := make([]Service, 0, len(serializedServices))
 
 	for _, service := range serializedServices {
-		majorParts := strings.Split(service, "@")
+		optionParts := strings.Split(service, "|")
+		majorParts := strings.Split(optionParts[0], "@")
 		if len(majorParts) != 2 {
 			return nil, fmt.Errorf(
 				"expected only one occurrence of '@', got %d in %s",
@@ -51,12 +65,159 @@ func parseServices(input string) ([]Service, error) {
 			return nil, fmt.Errorf("service endpoint URL does not start with 'http' in %s", service)
 		}
 
+		var events []string
+		var routes []Route
+		envelope := false
+		for _, option := range optionParts[1:] {
+			switch {
+			case option == optionEnvelope:
+				envelope = true
+			case strings.HasPrefix(option, optionRoute):
+				route, err := parseRoute(strings.TrimPrefix(option, optionRoute))
+				if err != nil {
+					return nil, fmt.Errorf("invalid route '%s' in %s: %w", option, service, err)
+				}
+				routes = append(routes, route)
+			case eventName.MatchString(option):
+				events = append(events, option)
+			default:
+				return nil, fmt.Errorf("invalid option '%s' in %s", option, service)
+			}
+		}
+
+		if minorParts[0] == "" && len(routes) == 0 {
+			return nil, fmt.Errorf("service without routes must have a label in %s", service)
+		}
+
 		services = append(services, Service{
 			Label:    minorParts[0],
 			Secret:   secret,
 			Endpoint: majorParts[1],
+			Events:   events,
+			Envelope: envelope,
+			Routes:   routes,
 		})
 	}
 
 	return services, nil
 }
+
+// parseRoute parses a route from semicolon-separated criteria such as "label=<id>;list=<id>;name=<regex>"
+func parseRoute(input string) (route Route, err error) {
+	for _, criterion := range strings.Split(input, ";") {
+		parts := strings.SplitN(criterion, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return route, fmt.Errorf("expected a non-empty 'key=value' criterion, got '%s'", criterion)
+		}
+
+		switch parts[0] {
+		case "label":
+			route.Label = parts[1]
+		case "list":
+			route.List = parts[1]
+		case "board":
+			route.Board = parts[1]
+		case "name":
+			route.Name = parts[1]
+		default:
+			return route, fmt.Errorf("unrecognized route criterion: '%s'", parts[0])
+		}
+	}
+	return route, ValidateRoute(route)
+}
+
+// ValidateRoute checks whether the route has at least one criterion and a valid card name regex
+func ValidateRoute(route Route) error {
+	if route.Label == "" && route.List == "" && route.Board == "" && route.Name == "" {
+		return fmt.Errorf("route must have at least one criterion")
+	}
+
+	if route.Name != "" {
+		if _, err := regexp.Compile(route.Name); err != nil {
+			return fmt.Errorf("invalid card name regex: %w", err)
+		}
+	}
+	return nil
+}
+
+// parsePositiveInt parses the given input as a positive integer, or returns the fallback value
+// if the input is empty
+func parsePositiveInt(input string, fallback int) (int, error) {
+	if input == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(input)
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("expected a positive integer, got %d", n)
+	}
+	return n, nil
+}
+
+// parseDuration parses the given input as a non-negative duration, or returns the fallback value
+// if the input is empty
+func parseDuration(input string, fallback time.Duration) (time.Duration, error) {
+	if input == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(input)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("expected a non-negative duration, got %v", d)
+	}
+	return d, nil
+}
+
+// parseBool parses the given input as a boolean, or returns the fallback value if the input is empty
+func parseBool(input string, fallback bool) (bool, error) {
+	if input == "" {
+		return fallback, nil
+	}
+	return strconv.ParseBool(input)
+}
+
+// parseApiKeys parses a comma-separated list of API keys in the form "name:hash:scope+scope",
+// where the hash is the hex-encoded SHA-256 hash of the key
+func parseApiKeys(input string) ([]ApiKey, error) {
+	keys := make([]ApiKey, 0)
+	if input == "" {
+		return keys, nil
+	}
+
+	for _, serialized := range strings.Split(input, ",") {
+		parts := strings.Split(serialized, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("expected an API key in the form 'name:hash:scopes', got %d parts", len(parts))
+		}
+
+		if !keyName.MatchString(parts[0]) {
+			return nil, fmt.Errorf("invalid API key name '%s'", parts[0])
+		}
+
+		if !sha256Hex.MatchString(parts[1]) {
+			return nil, fmt.Errorf("API key hash of '%s' is not a hex-encoded SHA-256 hash", parts[0])
+		}
+
+		scopes := strings.Split(parts[2], "+")
+		for _, scope := range scopes {
+			if scope != ScopeSync && scope != ScopeAdmin && scope != ScopeReadOnly {
+				return nil, fmt.Errorf("invalid scope '%s' for API key '%s'", scope, parts[0])
+			}
+		}
+
+		for _, key := range keys {
+			if key.Name == parts[0] {
+				return nil, fmt.Errorf("duplicate API key name '%s'", parts[0])
+			}
+		}
+
+		keys = append(keys, ApiKey{Name: parts[0], Hash: strings.ToLower(parts[1]), Scopes: scopes})
+	}
+	return keys, nil
+}
@@ -1,11 +1,18 @@
 package config
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 )
 
+// ignoreTemplates ignores the parsed templates of the mappings, which are compared by their expressions
+var ignoreTemplates = cmp.FilterPath(func(p cmp.Path) bool {
+	field, ok := p.Last().(cmp.StructField)
+	return ok && field.Name() == "templates"
+}, cmp.Ignore())
+
 func TestParseServices(t *testing.T) {
 	tt := []struct {
 		name     string
@@ -31,6 +38,68 @@ func TestParseServices(t *testing.T) {
 			isValid:  true,
 			services: []Service{{Label: "label", Secret: "aBcD1230XyZ", Endpoint: "http://example.com"}},
 		},
+		{
+			name:    "service with event subscriptions",
+			input:   "label:secret@http://example.com|card_archived|card_moved",
+			isValid: true,
+			services: []Service{{
+				Label:    "label",
+				Secret:   "secret",
+				Endpoint: "http://example.com",
+				Events:   []string{"card_archived", "card_moved"},
+			}},
+		},
+		{
+			name:    "service opted into event envelope",
+			input:   "label@http://example.com|envelope|card_moved",
+			isValid: true,
+			services: []Service{{
+				Label:    "label",
+				Endpoint: "http://example.com",
+				Events:   []string{"card_moved"},
+				Envelope: true,
+			}},
+		},
+		{
+			name:    "service with routes and without label",
+			input:   "@http://example.com|route:list=abc;name=^Buy |route:label=def|card_archived",
+			isValid: true,
+			services: []Service{{
+				Endpoint: "http://example.com",
+				Events:   []string{"card_archived"},
+				Routes:   []Route{{List: "abc", Name: "^Buy "}, {Label: "def"}},
+			}},
+		},
+		{
+			name:    "service without label and routes",
+			input:   "@http://example.com|card_archived",
+			isValid: false,
+		},
+		{
+			name:    "route with unrecognized criterion",
+			input:   "label@http://example.com|route:color=red",
+			isValid: false,
+		},
+		{
+			name:    "route with invalid name regex",
+			input:   "label@http://example.com|route:name=(",
+			isValid: false,
+		},
+		{
+			name:    "route without criteria",
+			input:   "label@http://example.com|route:",
+			isValid: false,
+		},
+		{
+			name:    "invalid event name",
+			input:   "label@http://example.com|Card-Moved",
+			isValid: false,
+		},
+		{
+			name:    "empty event name",
+			input:   "label@http://example.com|",
+			isValid: false,
+		},
 		{
 			name:    "endpoint URL does not start with 'http'",
 			input:   "label@example.com",
@@ -70,9 +139,68 @@ func TestParseServices(t *testing.T) {
 				t.Errorf("expected valid output? %v. Got error: %s", tc.isValid, err)
 				return
 			}
-			if diff := cmp.Diff(services, tc.services); diff != "" {
+			if diff := cmp.Diff(services, tc.services, ignoreTemplates); diff != "" {
 				t.Errorf("services diff: %s", diff)
 			}
 		})
 	}
 }
+
+func TestParseApiKeys(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+
+	tt := []struct {
+		name    string
+		input   string
+		isValid bool
+		keys    []ApiKey
+	}{
+		{
+			name:    "empty input",
+			input:   "",
+			isValid: true,
+			keys:    []ApiKey{},
+		},
+		{
+			name:    "multiple keys",
+			input:   "cron:" + hash + ":sync,ops:" + strings.ToUpper(hash) + ":admin+read-only",
+			isValid: true,
+			keys: []ApiKey{
+				{Name: "cron", Hash: hash, Scopes: []string{"sync"}},
+				{Name: "ops", Hash: hash, Scopes: []string{"admin", "read-only"}},
+			},
+		},
+		{
+			name:    "missing scopes",
+			input:   "cron:" + hash,
+			isValid: false,
+		},
+		{
+			name:    "invalid hash",
+			input:   "cron:s3cret:sync",
+			isValid: false,
+		},
+		{
+			name:    "invalid scope",
+			input:   "cron:" + hash + ":write",
+			isValid: false,
+		},
+		{
+			name:    "duplicate name",
+			input:   "cron:" + hash + ":sync,cron:" + hash + ":admin",
+			isValid: false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			keys, err := parseApiKeys(tc.input)
+			if (err == nil) != tc.isValid {
+				t.Fatalf("wanted valid: %t, got error: %v", tc.isValid, err)
+			}
+			if diff := cmp.Diff(tc.keys, keys); tc.isValid && diff != "" {
+				t.Errorf("API keys mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
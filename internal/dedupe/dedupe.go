@@ -0,0 +1,127 @@
+package dedupe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// Set is a bounded, concurrency-safe set of IDs where the oldest IDs are evicted once the capacity
+// is reached. If a file path is given, the set is persisted to the file upon each change.
+type Set struct {
+	capacity int
+	path     string
+	mu       sync.Mutex
+	ids      []string
+	index    map[string]struct{}
+}
+
+// New creates a set with the given capacity, loading the IDs from the given file if it exists.
+// The set is kept in memory only if the path is empty.
+func New(capacity int, path string) (*Set, error) {
+	s := &Set{
+		capacity: capacity,
+		path:     path,
+		ids:      make([]string, 0, capacity),
+		index:    make(map[string]struct{}, capacity),
+	}
+
+	if path == "" {
+		return s, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read processed IDs: %w", err)
+	}
+
+	var ids []string
+	if err = json.Unmarshal(b, &ids); err != nil {
+		return nil, fmt.Errorf("could not decode processed IDs: %w", err)
+	}
+	for _, id := range ids {
+		s.add(id)
+	}
+	return s, nil
+}
+
+// Claim adds the given ID to the set and returns true, unless the set already contains the ID,
+// in which case it returns false
+func (s *Set) Claim(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.index[id]; ok {
+		return false, nil
+	}
+
+	s.add(id)
+	return true, s.save()
+}
+
+// Contains checks if the set contains the given ID
+func (s *Set) Contains(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.index[id]
+	return ok
+}
+
+// Release removes the given ID from the set, so that it can be claimed again
+func (s *Set) Release(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.index[id]; !ok {
+		return nil
+	}
+
+	delete(s.index, id)
+	for i, v := range s.ids {
+		if v == id {
+			s.ids = append(s.ids[:i], s.ids[i+1:]...)
+			break
+		}
+	}
+	return s.save()
+}
+
+func (s *Set) add(id string) {
+	if _, ok := s.index[id]; ok {
+		return
+	}
+
+	if len(s.ids) >= s.capacity {
+		delete(s.index, s.ids[0])
+		s.ids = s.ids[1:]
+	}
+	s.ids = append(s.ids, id)
+	s.index[id] = struct{}{}
+}
+
+func (s *Set) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(s.ids)
+	if err != nil {
+		return fmt.Errorf("could not encode processed IDs: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0o600); err != nil {
+		return fmt.Errorf("could not write processed IDs: %w", err)
+	}
+	if err = os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("could not write processed IDs: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package dedupe
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "processed.json")
+	set, err := New(2, path)
+	if err != nil {
+		t.Fatalf("could not create set: %v", err)
+	}
+
+	assertClaim(t, set, "a", true)
+	assertClaim(t, set, "a", false)
+	assertClaim(t, set, "b", true)
+
+	// "a" is evicted once the capacity is exceeded
+	assertClaim(t, set, "c", true)
+	assertClaim(t, set, "a", true)
+
+	// released IDs can be claimed again
+	if err = set.Release("c"); err != nil {
+		t.Fatalf("could not release ID: %v", err)
+	}
+	assertClaim(t, set, "c", true)
+
+	// the set is restored from the file
+	set, err = New(2, path)
+	if err != nil {
+		t.Fatalf("could not load set: %v", err)
+	}
+	assertClaim(t, set, "a", false)
+	assertClaim(t, set, "c", false)
+	assertClaim(t, set, "b", true)
+}
+
+func TestInMemorySet(t *testing.T) {
+	set, err := New(1, "")
+	if err != nil {
+		t.Fatalf("could not create set: %v", err)
+	}
+	assertClaim(t, set, "a", true)
+	assertClaim(t, set, "a", false)
+
+	if !set.Contains("a") || set.Contains("b") {
+		t.Errorf("wanted the set to contain 'a' only")
+	}
+}
+
+func assertClaim(t *testing.T, set *Set, id string, want bool) {
+	t.Helper()
+	claimed, err := set.Claim(id)
+	if err != nil {
+		t.Fatalf("could not claim ID: %v", err)
+	}
+	if claimed != want {
+		t.Errorf("wanted claim of '%s' to be %t, got %t", id, want, claimed)
+	}
+}
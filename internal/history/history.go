@@ -0,0 +1,124 @@
+package history
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	EventCreated  = "created"
+	EventDeleted  = "deleted"
+	EventNotified = "notified"
+)
+
+// DefaultMaxEvents is the number of recent events remembered by the default history
+const DefaultMaxEvents = 200
+
+// Run represents the outcome of a single poll of a service
+type Run struct {
+	Service string
+	Time    time.Time
+	DryRun  bool
+	Created int
+	Deleted int
+	Invalid int
+	Errors  []string
+}
+
+// Ok checks whether the run has completed without any errors
+func (r Run) Ok() bool {
+	return len(r.Errors) == 0
+}
+
+// Event represents a card that has been created or deleted upon a sync, or notified to a service,
+// where the detail is e.g. the type of the Trello event that a service has been notified of
+type Event struct {
+	Type    string
+	Service string
+	Card    string
+	Detail  string
+	DryRun  bool
+	Time    time.Time
+}
+
+// Summary represents the history of a single service, where the card counts exclude dry runs
+type Summary struct {
+	Service string
+	LastRun Run
+	Runs    int
+	Created int
+	Deleted int
+}
+
+// History keeps the latest run of each service along with a bounded list of recent events in memory,
+// where the oldest events are forgotten once the capacity is reached
+type History struct {
+	maxEvents int
+	mu        sync.Mutex
+	summaries map[string]*Summary
+	events    []Event
+}
+
+// Default is the history that the syncs and notifications of entrello are recorded in
+var Default = New(DefaultMaxEvents)
+
+// New creates a history that remembers at most the given number of events
+func New(maxEvents int) *History {
+	return &History{maxEvents: maxEvents, summaries: make(map[string]*Summary)}
+}
+
+// AddRun records the given run as the latest run of its service
+func (h *History) AddRun(run Run) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	summary, ok := h.summaries[run.Service]
+	if !ok {
+		summary = &Summary{Service: run.Service}
+		h.summaries[run.Service] = summary
+	}
+
+	summary.LastRun = run
+	summary.Runs++
+	if !run.DryRun {
+		summary.Created += run.Created
+		summary.Deleted += run.Deleted
+	}
+}
+
+// AddEvent records the given event
+func (h *History) AddEvent(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.events) >= h.maxEvents {
+		h.events = h.events[1:]
+	}
+	h.events = append(h.events, event)
+}
+
+// Summaries returns the summary of each service that has been run so far, sorted by service name
+func (h *History) Summaries() []Summary {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	summaries := make([]Summary, 0, len(h.summaries))
+	for _, summary := range h.summaries {
+		summaries = append(summaries, *summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Service < summaries[j].Service })
+	return summaries
+}
+
+// Events returns the recent events, newest first
+func (h *History) Events() []Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	events := make([]Event, len(h.events))
+	for i, event := range h.events {
+		events[len(h.events)-1-i] = event
+	}
+	return events
+}
@@ -0,0 +1,82 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSummaries(t *testing.T) {
+	now := time.Now()
+	tt := []struct {
+		name string
+		runs []Run
+		want []Summary
+	}{
+		{
+			name: "no runs",
+			want: []Summary{},
+		},
+		{
+			name: "latest run of each service sorted by name",
+			runs: []Run{
+				{Service: "b", Time: now, Created: 2, Deleted: 1},
+				{Service: "a", Time: now, Created: 1},
+				{Service: "b", Time: now.Add(time.Minute), Created: 3, Errors: []string{"boom"}},
+			},
+			want: []Summary{
+				{Service: "a", LastRun: Run{Service: "a", Time: now, Created: 1}, Runs: 1, Created: 1},
+				{
+					Service: "b",
+					LastRun: Run{Service: "b", Time: now.Add(time.Minute), Created: 3, Errors: []string{"boom"}},
+					Runs:    2,
+					Created: 5,
+					Deleted: 1,
+				},
+			},
+		},
+		{
+			name: "dry runs are excluded from card counts",
+			runs: []Run{
+				{Service: "a", Time: now, Created: 1},
+				{Service: "a", Time: now, Created: 4, Deleted: 2, DryRun: true},
+			},
+			want: []Summary{
+				{
+					Service: "a",
+					LastRun: Run{Service: "a", Time: now, Created: 4, Deleted: 2, DryRun: true},
+					Runs:    2,
+					Created: 1,
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := New(10)
+			for _, run := range tc.runs {
+				h.AddRun(run)
+			}
+			if diff := cmp.Diff(tc.want, h.Summaries()); diff != "" {
+				t.Errorf("summaries mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEvents(t *testing.T) {
+	h := New(2)
+	for _, card := range []string{"a", "b", "c"} {
+		h.AddEvent(Event{Type: EventCreated, Service: "s", Card: card})
+	}
+
+	want := []Event{
+		{Type: EventCreated, Service: "s", Card: "c"},
+		{Type: EventCreated, Service: "s", Card: "b"},
+	}
+	if diff := cmp.Diff(want, h.Events()); diff != "" {
+		t.Errorf("events mismatch (-want, +got):\n%s", diff)
+	}
+}
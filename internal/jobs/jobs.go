@@ -0,0 +1,130 @@
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/utkuufuk/entrello/internal/services"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Progress represents the number of services polled so far out of the total number of services to poll
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// Job represents an asynchronous sync, along with the report of each polled service once it's finished
+type Job struct {
+	Id         string            `json:"id"`
+	Status     string            `json:"status"`
+	Progress   Progress          `json:"progress"`
+	Reports    []services.Report `json:"reports,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+}
+
+// RunFunc runs a sync, reporting its progress through the given function
+type RunFunc func(progress func(done, total int)) ([]services.Report, error)
+
+// Store runs jobs in the background and keeps a bounded in-memory history of them,
+// where the oldest jobs are forgotten once the capacity is reached
+type Store struct {
+	capacity int
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	jobs     map[string]*Job
+	order    []string
+}
+
+// New creates a job store that remembers at most the given number of jobs
+func New(capacity int) *Store {
+	return &Store{capacity: capacity, jobs: make(map[string]*Job, capacity)}
+}
+
+// Start runs the given function in a new job in the background, and returns the job immediately
+func (s *Store) Start(run RunFunc) (Job, error) {
+	id, err := newId()
+	if err != nil {
+		return Job{}, fmt.Errorf("could not generate job ID: %w", err)
+	}
+
+	job := &Job{Id: id, Status: StatusPending, CreatedAt: time.Now().UTC()}
+
+	s.mu.Lock()
+	if len(s.order) >= s.capacity {
+		delete(s.jobs, s.order[0])
+		s.order = s.order[1:]
+	}
+	s.jobs[id] = job
+	s.order = append(s.order, id)
+	snapshot := *job
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(job, run)
+	}()
+	return snapshot, nil
+}
+
+// Wait blocks until all the jobs that have been started are finished
+func (s *Store) Wait() {
+	s.wg.Wait()
+}
+
+// Get returns a snapshot of the job with the given ID, if it's still remembered
+func (s *Store) Get(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+func (s *Store) run(job *Job, run RunFunc) {
+	s.update(job, func() { job.Status = StatusRunning })
+
+	reports, err := run(func(done, total int) {
+		s.update(job, func() { job.Progress = Progress{done, total} })
+	})
+
+	s.update(job, func() {
+		now := time.Now().UTC()
+		job.FinishedAt = &now
+		job.Reports = reports
+		job.Status = StatusSucceeded
+		if err != nil {
+			job.Status = StatusFailed
+			job.Error = err.Error()
+		}
+	})
+}
+
+func (s *Store) update(job *Job, f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f()
+}
+
+func newId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
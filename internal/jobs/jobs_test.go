@@ -0,0 +1,121 @@
+package jobs
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/utkuufuk/entrello/internal/services"
+)
+
+func TestStore(t *testing.T) {
+	tt := []struct {
+		name    string
+		reports []services.Report
+		err     error
+		status  string
+	}{
+		{
+			name:    "successful job",
+			reports: []services.Report{{Service: "a"}, {Service: "b"}},
+			status:  StatusSucceeded,
+		},
+		{
+			name:   "failed job",
+			err:    fmt.Errorf("could not load board"),
+			status: StatusFailed,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			store := New(10)
+			release := make(chan struct{})
+			job, err := store.Start(func(progress func(done, total int)) ([]services.Report, error) {
+				progress(1, 2)
+				<-release
+				progress(2, 2)
+				return tc.reports, tc.err
+			})
+			if err != nil {
+				t.Fatalf("could not start job: %v", err)
+			}
+			if job.Status != StatusPending {
+				t.Errorf("wanted status %s, got %s", StatusPending, job.Status)
+			}
+
+			close(release)
+			job = waitForJob(t, store, job.Id)
+
+			if job.Status != tc.status {
+				t.Errorf("wanted status %s, got %s", tc.status, job.Status)
+			}
+			if len(job.Reports) != len(tc.reports) {
+				t.Errorf("wanted %d reports, got %d", len(tc.reports), len(job.Reports))
+			}
+			if job.Progress != (Progress{2, 2}) {
+				t.Errorf("wanted progress 2/2, got %v", job.Progress)
+			}
+			if tc.err != nil && job.Error != tc.err.Error() {
+				t.Errorf("wanted error '%v', got '%s'", tc.err, job.Error)
+			}
+		})
+	}
+}
+
+func TestStoreCapacity(t *testing.T) {
+	store := New(2)
+	ids := make([]string, 0)
+	for i := 0; i < 3; i++ {
+		job, err := store.Start(func(func(done, total int)) ([]services.Report, error) {
+			return nil, nil
+		})
+		if err != nil {
+			t.Fatalf("could not start job: %v", err)
+		}
+		ids = append(ids, job.Id)
+	}
+
+	if _, ok := store.Get(ids[0]); ok {
+		t.Errorf("wanted the oldest job to be forgotten")
+	}
+	for _, id := range ids[1:] {
+		if _, ok := store.Get(id); !ok {
+			t.Errorf("wanted job %s to be remembered", id)
+		}
+	}
+}
+
+func waitForJob(t *testing.T, store *Store, id string) Job {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		job, ok := store.Get(id)
+		if !ok {
+			t.Fatalf("job %s not found", id)
+		}
+		if job.FinishedAt != nil {
+			return job
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish in time", id)
+	return Job{}
+}
+
+func TestStoreWait(t *testing.T) {
+	store := New(10)
+	finished := false
+	job, err := store.Start(func(func(done, total int)) ([]services.Report, error) {
+		time.Sleep(10 * time.Millisecond)
+		finished = true
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("could not start job: %v", err)
+	}
+
+	store.Wait()
+	if !finished {
+		t.Errorf("wanted Wait to block until job %s is finished", job.Id)
+	}
+}
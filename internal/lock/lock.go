@@ -0,0 +1,34 @@
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrLocked is returned when the lock is held by another process
+var ErrLocked = errors.New("lock is held by another process")
+
+// File is an exclusive lock on a file, which is released automatically by the operating system
+// if the process holding it exits without releasing it
+type File struct {
+	f *os.File
+}
+
+// Acquire acquires the lock on the file at the given path without blocking,
+// creating the file if it doesn't exist. Returns ErrLocked if another process holds the lock.
+func Acquire(path string) (*File, error) {
+	f, err := lock(path)
+	if err != nil {
+		return nil, err
+	}
+	return &File{f}, nil
+}
+
+// Release releases the lock by closing the file
+func (l *File) Release() error {
+	if err := l.f.Close(); err != nil {
+		return fmt.Errorf("could not release lock: %w", err)
+	}
+	return nil
+}
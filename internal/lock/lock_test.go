@@ -0,0 +1,30 @@
+package lock
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "entrello.lock")
+
+	l, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("could not acquire lock: %v", err)
+	}
+
+	if _, err = Acquire(path); !errors.Is(err, ErrLocked) {
+		t.Errorf("wanted ErrLocked while the lock is held, got %v", err)
+	}
+
+	if err = l.Release(); err != nil {
+		t.Fatalf("could not release lock: %v", err)
+	}
+
+	l, err = Acquire(path)
+	if err != nil {
+		t.Fatalf("could not acquire released lock: %v", err)
+	}
+	l.Release()
+}
@@ -0,0 +1,38 @@
+//go:build windows
+
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// errorSharingViolation is the ERROR_SHARING_VIOLATION Windows error code
+const errorSharingViolation syscall.Errno = 32
+
+// lock opens the file without sharing it, so that no other process can open it until it's closed
+func lock(path string) (*os.File, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lock file path: %w", err)
+	}
+
+	h, err := syscall.CreateFile(
+		name,
+		syscall.GENERIC_READ|syscall.GENERIC_WRITE,
+		0,
+		nil,
+		syscall.OPEN_ALWAYS,
+		syscall.FILE_ATTRIBUTE_NORMAL,
+		0,
+	)
+	if errors.Is(err, errorSharingViolation) {
+		return nil, ErrLocked
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not open lock file: %w", err)
+	}
+	return os.NewFile(uintptr(h), path), nil
+}
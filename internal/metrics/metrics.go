@@ -0,0 +1,264 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContentType is the content type of the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the default histogram buckets in seconds, suitable for HTTP request latencies
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+// metric is a family of time series that can write itself in the Prometheus text format
+type metric interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry is a set of metrics that can be exposed together
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+// Default is the registry that the metrics of entrello are registered in
+var Default = &Registry{}
+
+// NewCounter creates a counter with the given label names in the default registry
+func NewCounter(name, help string, labelNames ...string) *Counter {
+	return Default.NewCounter(name, help, labelNames...)
+}
+
+// NewHistogram creates a histogram with the given buckets and label names in the default registry
+func NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	return Default.NewHistogram(name, help, buckets, labelNames...)
+}
+
+// NewCounter creates a counter with the given label names and registers it
+func (r *Registry) NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{family: newFamily(name, help, labelNames), values: make(map[string]float64)}
+	r.register(c)
+	return c
+}
+
+// NewHistogram creates a histogram with the given buckets and label names and registers it
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	h := &Histogram{
+		family:  newFamily(name, help, labelNames),
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+	r.register(h)
+	return h
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes all the metrics in the registry to w in the Prometheus text format, sorted by name
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	metrics := append([]metric{}, r.metrics...)
+	r.mu.Unlock()
+
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name() < metrics[j].name() })
+
+	var buf bytes.Buffer
+	for _, m := range metrics {
+		m.write(&buf)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Push sends all the metrics in the registry to the given Prometheus Pushgateway URL,
+// e.g. "http://pushgateway:9091/metrics/job/entrello"
+func (r *Registry) Push(url string) error {
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(url, ContentType, &buf)
+	if err != nil {
+		return fmt.Errorf("could not push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("could not push metrics: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// family contains the fields shared by all metric types
+type family struct {
+	metricName string
+	help       string
+	labelNames []string
+	mu         sync.Mutex
+}
+
+func newFamily(name, help string, labelNames []string) family {
+	return family{metricName: name, help: help, labelNames: labelNames}
+}
+
+func (f *family) name() string {
+	return f.metricName
+}
+
+// key serializes the given label values in the Prometheus format, e.g. `service="a",outcome="b"`
+func (f *family) key(labelValues []string) string {
+	if len(labelValues) != len(f.labelNames) {
+		panic(fmt.Sprintf(
+			"metric %s expects %d label values, got %d",
+			f.metricName,
+			len(f.labelNames),
+			len(labelValues),
+		))
+	}
+
+	pairs := make([]string, len(labelValues))
+	for i, value := range labelValues {
+		pairs[i] = fmt.Sprintf("%s=\"%s\"", f.labelNames[i], labelEscaper.Replace(value))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f *family) writeHeader(w io.Writer, metricType string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.metricName, helpEscaper.Replace(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.metricName, metricType)
+}
+
+// Counter is a family of monotonically increasing values partitioned by labels
+type Counter struct {
+	family
+	values map[string]float64
+}
+
+// Inc increments the counter with the given label values by one
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increments the counter with the given label values by v
+func (c *Counter) Add(v float64, labelValues ...string) {
+	key := c.key(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] += v
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writeHeader(w, "counter")
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.metricName, braces(key), formatFloat(c.values[key]))
+	}
+}
+
+// Histogram is a family of observation distributions partitioned by labels
+type Histogram struct {
+	family
+	buckets []float64
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Observe records the given value in the histogram with the given label values
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := h.key(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+
+	for i, bound := range h.buckets {
+		if v <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+// Since records the time elapsed since the given instant in seconds
+func (h *Histogram) Since(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.writeHeader(w, "histogram")
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		prefix := key
+		if prefix != "" {
+			prefix += ","
+		}
+
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket{%sle=\"%s\"} %d\n", h.metricName, prefix, formatFloat(bound), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", h.metricName, prefix, s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, braces(key), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, braces(key), s.count)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func braces(key string) string {
+	if key == "" {
+		return ""
+	}
+	return "{" + key + "}"
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
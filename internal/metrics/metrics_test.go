@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"bytes"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestRegistryWrite(t *testing.T) {
+	r := &Registry{}
+	polls := r.NewCounter("test_polls_total", "Number of polls.", "service", "outcome")
+	latency := r.NewHistogram("test_latency_seconds", "Request latency.", []float64{0.1, 1}, "service")
+	duration := r.NewHistogram("test_duration_seconds", "Duration of\nthe \\ runs.", []float64{0.5, 2.5})
+	empty := r.NewCounter("test_empty_total", "Number of nothing.")
+	r.NewCounter("test_unused_total", "Counter without any values.", "service")
+
+	polls.Inc("b", "success")
+	polls.Add(2, "a \"quoted\"", "failure")
+	polls.Add(0.5, "back\\slash", "multi\nline")
+	latency.Observe(0.05, "a")
+	latency.Observe(0.5, "a")
+	latency.Observe(0.1, "b \"c\"")
+	duration.Observe(3)
+	duration.Observe(0.25)
+	empty.Inc()
+
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatalf("could not write metrics: %v", err)
+	}
+
+	golden := filepath.Join("testdata", "registry.golden")
+	if *update {
+		if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+			t.Fatalf("could not update golden file: %v", err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("could not read golden file: %v", err)
+	}
+	if got := buf.String(); got != string(want) {
+		t.Errorf("wanted:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestRegistryPush(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var buf bytes.Buffer
+		buf.ReadFrom(req.Body)
+		body = buf.String()
+		if req.Header.Get("Content-Type") != ContentType {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	r := &Registry{}
+	r.NewCounter("test_total", "Test.").Inc()
+
+	if err := r.Push(server.URL); err != nil {
+		t.Fatalf("could not push metrics: %v", err)
+	}
+	if body != "# HELP test_total Test.\n# TYPE test_total counter\ntest_total 1\n" {
+		t.Errorf("unexpected pushed body: %q", body)
+	}
+}
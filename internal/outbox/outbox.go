@@ -0,0 +1,292 @@
+package outbox
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	pendingDir = "pending"
+	deadDir    = "dead"
+	maxBackoff = time.Hour
+)
+
+// ErrNotFound is returned when a message with the given ID does not exist
+var ErrNotFound = errors.New("message not found")
+
+// Message represents a notification to be delivered to a service, where the service is referred to
+// by its key so that neither its secret is persisted nor its configuration gets stale
+type Message struct {
+	Id             string          `json:"id"`
+	IdempotencyKey string          `json:"idempotency_key"`
+	ServiceKey     string          `json:"service_key"`
+	Event          string          `json:"event"`
+	Body           json.RawMessage `json:"body"`
+	CreatedAt      time.Time       `json:"created_at"`
+	Attempts       int             `json:"attempts"`
+	NextAttempt    time.Time       `json:"next_attempt"`
+	LastError      string          `json:"last_error,omitempty"`
+}
+
+// Outbox is a persistent queue of messages where each message is stored as a JSON file, either in
+// the pending directory until it's delivered, or in the dead letter directory after it has failed
+// too many times. Since the file system is the only source of truth, multiple processes (e.g. the
+// server and the CLI) can work on the same outbox.
+type Outbox struct {
+	dir         string
+	maxAttempts int
+	backoff     time.Duration
+	mu          sync.Mutex
+}
+
+// New creates an outbox in the given directory, where a message is moved to the dead letter store
+// after maxAttempts failed delivery attempts and the delay between attempts starts at backoff
+func New(dir string, maxAttempts int, backoff time.Duration) (*Outbox, error) {
+	for _, d := range []string{pendingDir, deadDir} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0o700); err != nil {
+			return nil, fmt.Errorf("could not create outbox directory: %w", err)
+		}
+	}
+	return &Outbox{dir: dir, maxAttempts: maxAttempts, backoff: backoff}, nil
+}
+
+// Enqueue persists a new message to be delivered to the service with the given key, where the idempotency
+// key lets the service recognize redeliveries of the same message, and defaults to the message ID
+func (o *Outbox) Enqueue(
+	serviceKey string,
+	event string,
+	idempotencyKey string,
+	body json.RawMessage,
+) (Message, error) {
+	id, err := newId()
+	if err != nil {
+		return Message{}, fmt.Errorf("could not generate message ID: %w", err)
+	}
+
+	if idempotencyKey == "" {
+		idempotencyKey = id
+	}
+
+	now := time.Now().UTC()
+	msg := Message{
+		Id:             id,
+		IdempotencyKey: idempotencyKey,
+		ServiceKey:     serviceKey,
+		Event:          event,
+		Body:           body,
+		CreatedAt:      now,
+		NextAttempt:    now,
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return msg, o.write(pendingDir, msg)
+}
+
+// Pending returns the pending messages ordered by creation time
+func (o *Outbox) Pending() ([]Message, error) {
+	return o.list(pendingDir)
+}
+
+// DeadLetters returns the dead letters ordered by creation time
+func (o *Outbox) DeadLetters() ([]Message, error) {
+	return o.list(deadDir)
+}
+
+// Replay moves the dead letter with the given ID back to the pending messages
+// with its attempt count reset, so that it will be delivered as soon as possible
+func (o *Outbox) Replay(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	msg, err := o.read(deadDir, id)
+	if err != nil {
+		return err
+	}
+
+	msg.Attempts = 0
+	msg.NextAttempt = time.Now().UTC()
+	msg.LastError = ""
+	if err = o.write(pendingDir, msg); err != nil {
+		return err
+	}
+	return os.Remove(o.path(deadDir, id))
+}
+
+// Process attempts to deliver each pending message that is due concurrently, using the given deliver
+// function. Delivered messages are removed, and failed ones are either rescheduled with exponential
+// backoff or moved to the dead letter store if they have run out of attempts. A failed delivery does
+// not affect the delivery of other messages.
+func (o *Outbox) Process(deliver func(Message) error) error {
+	messages, err := o.Pending()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	errs := make(chan error, len(messages))
+	var wg sync.WaitGroup
+	for _, msg := range messages {
+		if msg.NextAttempt.After(now) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(msg Message) {
+			defer wg.Done()
+			err := deliver(msg)
+
+			o.mu.Lock()
+			defer o.mu.Unlock()
+			if err = o.complete(msg, err); err != nil {
+				errs <- err
+			}
+		}(msg)
+	}
+	wg.Wait()
+	close(errs)
+
+	// the delivery errors are recorded in the messages, so only the outbox errors are returned
+	return <-errs
+}
+
+// Run processes the outbox periodically with the given interval until the done channel is closed,
+// passing any processing errors to onError
+func (o *Outbox) Run(
+	interval time.Duration,
+	deliver func(Message) error,
+	done <-chan struct{},
+	onError func(error),
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := o.Process(deliver); err != nil {
+			onError(err)
+		}
+
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// complete records the outcome of a delivery attempt
+func (o *Outbox) complete(msg Message, deliveryErr error) error {
+	if deliveryErr == nil {
+		err := os.Remove(o.path(pendingDir, msg.Id))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	msg.Attempts++
+	msg.LastError = deliveryErr.Error()
+
+	if msg.Attempts >= o.maxAttempts {
+		if err := o.write(deadDir, msg); err != nil {
+			return err
+		}
+		return os.Remove(o.path(pendingDir, msg.Id))
+	}
+
+	msg.NextAttempt = time.Now().UTC().Add(o.nextBackoff(msg.Attempts))
+	return o.write(pendingDir, msg)
+}
+
+// nextBackoff returns the delay before the next attempt, doubled after each failed attempt
+func (o *Outbox) nextBackoff(attempts int) time.Duration {
+	backoff := o.backoff
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+func (o *Outbox) list(dir string) ([]Message, error) {
+	entries, err := ioutil.ReadDir(filepath.Join(o.dir, dir))
+	if err != nil {
+		return nil, fmt.Errorf("could not list outbox directory: %w", err)
+	}
+
+	messages := make([]Message, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		msg, err := o.read(dir, strings.TrimSuffix(entry.Name(), ".json"))
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+	return messages, nil
+}
+
+func (o *Outbox) read(dir, id string) (msg Message, err error) {
+	b, err := ioutil.ReadFile(o.path(dir, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return msg, ErrNotFound
+	}
+	if err != nil {
+		return msg, fmt.Errorf("could not read message %s: %w", id, err)
+	}
+
+	if err = json.Unmarshal(b, &msg); err != nil {
+		return msg, fmt.Errorf("could not decode message %s: %w", id, err)
+	}
+	return msg, nil
+}
+
+// write persists the message atomically by writing to a temporary file first
+func (o *Outbox) write(dir string, msg Message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("could not encode message %s: %w", msg.Id, err)
+	}
+
+	tmp := o.path(dir, msg.Id) + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0o600); err != nil {
+		return fmt.Errorf("could not write message %s: %w", msg.Id, err)
+	}
+	if err = os.Rename(tmp, o.path(dir, msg.Id)); err != nil {
+		return fmt.Errorf("could not write message %s: %w", msg.Id, err)
+	}
+	return nil
+}
+
+func (o *Outbox) path(dir, id string) string {
+	return filepath.Join(o.dir, dir, filepath.Base(id)+".json")
+}
+
+func newId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
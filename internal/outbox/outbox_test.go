@@ -0,0 +1,107 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestProcess(t *testing.T) {
+	box, err := New(t.TempDir(), 2, 0)
+	if err != nil {
+		t.Fatalf("could not create outbox: %v", err)
+	}
+
+	delivered, err := box.Enqueue("label@http://example.com", "card_archived", "a1", json.RawMessage(`{"id":"c1"}`))
+	if err != nil {
+		t.Fatalf("could not enqueue message: %v", err)
+	}
+	failing, err := box.Enqueue("label@http://example.com", "card_moved", "", json.RawMessage(`{"id":"c2"}`))
+	if err != nil {
+		t.Fatalf("could not enqueue message: %v", err)
+	}
+
+	if delivered.IdempotencyKey != "a1" || failing.IdempotencyKey != failing.Id {
+		t.Errorf("wanted idempotency keys to default to message IDs, got %v and %v", delivered, failing)
+	}
+
+	deliver := func(msg Message) error {
+		if msg.Id == failing.Id {
+			return fmt.Errorf("service unavailable")
+		}
+		return nil
+	}
+
+	// first attempt: one message is delivered, the other one is rescheduled
+	if err = box.Process(deliver); err != nil {
+		t.Fatalf("could not process outbox: %v", err)
+	}
+	assertMessages(t, box.Pending, []string{failing.Id})
+	assertMessages(t, box.DeadLetters, []string{})
+
+	pending, _ := box.Pending()
+	if pending[0].Attempts != 1 || pending[0].LastError != "service unavailable" {
+		t.Errorf("wanted 1 attempt with the last error recorded, got %v", pending[0])
+	}
+
+	// second attempt: the failing message runs out of attempts
+	if err = box.Process(deliver); err != nil {
+		t.Fatalf("could not process outbox: %v", err)
+	}
+	assertMessages(t, box.Pending, []string{})
+	assertMessages(t, box.DeadLetters, []string{failing.Id})
+
+	// replay: the dead letter is pending again with its attempts reset
+	if err = box.Replay(failing.Id); err != nil {
+		t.Fatalf("could not replay dead letter: %v", err)
+	}
+	assertMessages(t, box.Pending, []string{failing.Id})
+	assertMessages(t, box.DeadLetters, []string{})
+
+	pending, _ = box.Pending()
+	if pending[0].Attempts != 0 || string(pending[0].Body) != `{"id":"c2"}` {
+		t.Errorf("wanted the original message with no attempts, got %v", pending[0])
+	}
+
+	if err = box.Replay(delivered.Id); err != ErrNotFound {
+		t.Errorf("expected error to be %v, got '%v'", ErrNotFound, err)
+	}
+}
+
+func TestNextBackoff(t *testing.T) {
+	box := &Outbox{backoff: time.Second}
+	tt := []struct {
+		attempts int
+		backoff  time.Duration
+	}{
+		{attempts: 1, backoff: time.Second},
+		{attempts: 2, backoff: 2 * time.Second},
+		{attempts: 5, backoff: 16 * time.Second},
+		{attempts: 50, backoff: maxBackoff},
+	}
+
+	for _, tc := range tt {
+		t.Run(fmt.Sprintf("%d attempts", tc.attempts), func(t *testing.T) {
+			if backoff := box.nextBackoff(tc.attempts); backoff != tc.backoff {
+				t.Errorf("wanted %v, got %v", tc.backoff, backoff)
+			}
+		})
+	}
+}
+
+func assertMessages(t *testing.T, list func() ([]Message, error), ids []string) {
+	t.Helper()
+	messages, err := list()
+	if err != nil {
+		t.Fatalf("could not list messages: %v", err)
+	}
+	if len(messages) != len(ids) {
+		t.Fatalf("wanted %d messages, got %d", len(ids), len(messages))
+	}
+	for i, msg := range messages {
+		if msg.Id != ids[i] {
+			t.Errorf("wanted message %s, got %s", ids[i], msg.Id)
+		}
+	}
+}
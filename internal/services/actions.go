@@ -0,0 +1,164 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/pkg/trello"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	ActionCreateCard = "create_card"
+	ActionAddComment = "add_comment"
+	ActionSetDue     = "set_due"
+	ActionUnarchive  = "unarchive"
+	ActionMove       = "move"
+
+	maxActions = 20
+)
+
+// Action represents a follow-up board action requested by a service in response to a notification
+type Action struct {
+	Type        string     `json:"type"`
+	CardId      string     `json:"card_id"`
+	ListId      string     `json:"list_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Text        string     `json:"text"`
+	Due         *time.Time `json:"due"`
+}
+
+// actionClient is the subset of the Trello client that the follow-up actions are applied through
+type actionClient interface {
+	HasList(listId string) (bool, error)
+	GetCard(id string) (trello.Card, error)
+	IsOnBoard(card trello.Card) bool
+	CreateCard(card trello.Card, label string, listId string) error
+	AddComment(cardId, text string) error
+	SetDueDate(cardId string, dueDate *time.Time) error
+	UnarchiveCard(cardId string) error
+	MoveCard(cardId, listId string) error
+}
+
+// actionsResponse represents the optional JSON response body of a service to a notification
+type actionsResponse struct {
+	Actions []Action `json:"actions"`
+}
+
+// decodeActions decodes the follow-up actions in a notification response body, and validates them
+func decodeActions(r io.Reader) ([]Action, error) {
+	var resp actionsResponse
+	if err := json.NewDecoder(r).Decode(&resp); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not decode actions: %w", err)
+	}
+
+	if len(resp.Actions) > maxActions {
+		return nil, fmt.Errorf("expected at most %d actions, got %d", maxActions, len(resp.Actions))
+	}
+
+	for i, action := range resp.Actions {
+		if err := action.validate(); err != nil {
+			return nil, fmt.Errorf("invalid action %d: %w", i, err)
+		}
+	}
+	return resp.Actions, nil
+}
+
+// validate checks if the action has all the fields required by its type
+func (a Action) validate() error {
+	switch a.Type {
+	case ActionCreateCard:
+		_, err := trello.NewCard(a.Name, a.Description, a.Due)
+		return err
+	case ActionAddComment:
+		if a.Text == "" {
+			return fmt.Errorf("comment text cannot be blank")
+		}
+	case ActionMove:
+		if a.ListId == "" {
+			return fmt.Errorf("list ID cannot be blank")
+		}
+	case ActionSetDue, ActionUnarchive:
+	default:
+		return fmt.Errorf("unrecognized action type: '%s'", a.Type)
+	}
+
+	if a.CardId == "" {
+		return fmt.Errorf("card ID cannot be blank")
+	}
+	return nil
+}
+
+// applyActions applies the given actions through the Trello client on behalf of the service, where
+// the service can only create cards with its own label, and act on existing cards with its own label.
+// Returns an error for each action that could not be applied.
+func applyActions(actions []Action, service config.Service, client actionClient) []error {
+	errs := make([]error, 0)
+	for i, action := range actions {
+		if err := applyAction(action, service, client); err != nil {
+			errs = append(errs, fmt.Errorf("could not apply action %d (%s): %w", i, action.Type, err))
+		}
+	}
+	return errs
+}
+
+// applyAction applies a single action, after checking that the list, if any, is on the board, and that
+// the target card is on the board and has the service label
+func applyAction(action Action, service config.Service, client actionClient) error {
+	if service.Label == "" {
+		return fmt.Errorf("service has no label to act on")
+	}
+
+	if action.ListId != "" {
+		ok, err := client.HasList(action.ListId)
+		if err != nil {
+			return fmt.Errorf("could not fetch list: %w", err)
+		}
+		if !ok {
+			return fmt.Errorf("list %s is not on the board", action.ListId)
+		}
+	}
+
+	if action.Type == ActionCreateCard {
+		listId := action.ListId
+		if listId == "" {
+			listId = service.List
+		}
+		if listId == "" {
+			return fmt.Errorf("list ID cannot be blank")
+		}
+
+		card, err := trello.NewCard(action.Name, action.Description, action.Due)
+		if err != nil {
+			return err
+		}
+		return client.CreateCard(card, service.Label, listId)
+	}
+
+	card, err := client.GetCard(action.CardId)
+	if err != nil {
+		return fmt.Errorf("could not fetch card: %w", err)
+	}
+	if !client.IsOnBoard(card) || !slices.Contains(card.IDLabels, service.Label) {
+		return fmt.Errorf("card %s does not have the service label", action.CardId)
+	}
+
+	switch action.Type {
+	case ActionAddComment:
+		return client.AddComment(action.CardId, action.Text)
+	case ActionSetDue:
+		return client.SetDueDate(action.CardId, action.Due)
+	case ActionUnarchive:
+		return client.UnarchiveCard(action.CardId)
+	case ActionMove:
+		return client.MoveCard(action.CardId, action.ListId)
+	}
+	return fmt.Errorf("unrecognized action type: '%s'", action.Type)
+}
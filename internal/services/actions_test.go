@@ -0,0 +1,247 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/utkuufuk/entrello/internal/config"
+	entrello "github.com/utkuufuk/entrello/pkg/trello"
+	"golang.org/x/exp/slices"
+)
+
+func TestDecodeActions(t *testing.T) {
+	tt := []struct {
+		name       string
+		body       string
+		numActions int
+		err        error
+	}{
+		{
+			name: "empty body",
+			body: "",
+		},
+		{
+			name: "no actions",
+			body: `{}`,
+		},
+		{
+			name: "valid actions",
+			body: `{"actions": [
+				{"type": "create_card", "name": "Drink water", "due": "2022-04-02T09:00:00Z"},
+				{"type": "add_comment", "card_id": "c1", "text": "Streak: 5 days"},
+				{"type": "set_due", "card_id": "c1", "due": null},
+				{"type": "unarchive", "card_id": "c1"},
+				{"type": "move", "card_id": "c1", "list_id": "l1"}
+			]}`,
+			numActions: 5,
+		},
+		{
+			name: "unrecognized action type",
+			body: `{"actions": [{"type": "delete_card", "card_id": "c1"}]}`,
+			err:  fmt.Errorf("invalid action 0: unrecognized action type: 'delete_card'"),
+		},
+		{
+			name: "card without name",
+			body: `{"actions": [{"type": "create_card"}]}`,
+			err:  fmt.Errorf("invalid action 0: card name cannot be blank"),
+		},
+		{
+			name: "comment without text",
+			body: `{"actions": [{"type": "unarchive", "card_id": "c1"}, {"type": "add_comment", "card_id": "c1"}]}`,
+			err:  fmt.Errorf("invalid action 1: comment text cannot be blank"),
+		},
+		{
+			name: "move without list",
+			body: `{"actions": [{"type": "move", "card_id": "c1"}]}`,
+			err:  fmt.Errorf("invalid action 0: list ID cannot be blank"),
+		},
+		{
+			name: "unarchive without card",
+			body: `{"actions": [{"type": "unarchive"}]}`,
+			err:  fmt.Errorf("invalid action 0: card ID cannot be blank"),
+		},
+		{
+			name: "too many actions",
+			body: fmt.Sprintf(
+				`{"actions": [%s{"type": "unarchive", "card_id": "c1"}]}`,
+				strings.Repeat(`{"type": "unarchive", "card_id": "c1"},`, maxActions),
+			),
+			err: fmt.Errorf("expected at most 20 actions, got 21"),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			actions, err := decodeActions(strings.NewReader(tc.body))
+
+			if err != nil || tc.err != nil {
+				if err == nil || tc.err == nil || err.Error() != tc.err.Error() {
+					t.Errorf("expected error to be %v, got '%v'", tc.err, err)
+				}
+				return
+			}
+
+			if len(actions) != tc.numActions {
+				t.Errorf("wanted %d actions, got %d", tc.numActions, len(actions))
+			}
+		})
+	}
+}
+
+// fakeActionClient is an in-memory board that records the changes applied through it
+type fakeActionClient struct {
+	boardId string
+	lists   []string
+	cards   map[string]entrello.Card
+	applied []string
+}
+
+func (c *fakeActionClient) HasList(listId string) (bool, error) {
+	return slices.Contains(c.lists, listId), nil
+}
+
+func (c *fakeActionClient) GetCard(id string) (entrello.Card, error) {
+	card, ok := c.cards[id]
+	if !ok {
+		return nil, fmt.Errorf("card not found")
+	}
+	return card, nil
+}
+
+func (c *fakeActionClient) IsOnBoard(card entrello.Card) bool {
+	return card != nil && card.IDBoard == c.boardId
+}
+
+func (c *fakeActionClient) CreateCard(card entrello.Card, label string, listId string) error {
+	c.applied = append(c.applied, fmt.Sprintf("create %s %s %s", card.Name, label, listId))
+	return nil
+}
+
+func (c *fakeActionClient) AddComment(cardId, text string) error {
+	c.applied = append(c.applied, fmt.Sprintf("comment %s %s", cardId, text))
+	return nil
+}
+
+func (c *fakeActionClient) SetDueDate(cardId string, dueDate *time.Time) error {
+	c.applied = append(c.applied, fmt.Sprintf("due %s", cardId))
+	return nil
+}
+
+func (c *fakeActionClient) UnarchiveCard(cardId string) error {
+	c.applied = append(c.applied, fmt.Sprintf("unarchive %s", cardId))
+	return nil
+}
+
+func (c *fakeActionClient) MoveCard(cardId, listId string) error {
+	c.applied = append(c.applied, fmt.Sprintf("move %s %s", cardId, listId))
+	return nil
+}
+
+func TestApplyAction(t *testing.T) {
+	service := config.Service{Label: "label", List: "l1"}
+	tt := []struct {
+		name    string
+		noLabel bool
+		action  Action
+		applied string
+		err     error
+	}{
+		{
+			name:    "create card in the service list",
+			action:  Action{Type: ActionCreateCard, Name: "Drink water"},
+			applied: "create Drink water label l1",
+		},
+		{
+			name:    "create card in another list on the board",
+			action:  Action{Type: ActionCreateCard, Name: "Drink water", ListId: "l2"},
+			applied: "create Drink water label l2",
+		},
+		{
+			name:   "create card in unknown list",
+			action: Action{Type: ActionCreateCard, Name: "Drink water", ListId: "foreign"},
+			err:    fmt.Errorf("list foreign is not on the board"),
+		},
+		{
+			name:    "add comment",
+			action:  Action{Type: ActionAddComment, CardId: "own", Text: "Streak: 5 days"},
+			applied: "comment own Streak: 5 days",
+		},
+		{
+			name:    "set due date",
+			action:  Action{Type: ActionSetDue, CardId: "own"},
+			applied: "due own",
+		},
+		{
+			name:    "unarchive",
+			action:  Action{Type: ActionUnarchive, CardId: "own"},
+			applied: "unarchive own",
+		},
+		{
+			name:    "move",
+			action:  Action{Type: ActionMove, CardId: "own", ListId: "l2"},
+			applied: "move own l2",
+		},
+		{
+			name:   "move to unknown list",
+			action: Action{Type: ActionMove, CardId: "own", ListId: "foreign"},
+			err:    fmt.Errorf("list foreign is not on the board"),
+		},
+		{
+			name:   "card on another board",
+			action: Action{Type: ActionAddComment, CardId: "foreign", Text: "hi"},
+			err:    fmt.Errorf("card foreign does not have the service label"),
+		},
+		{
+			name:   "card without the service label",
+			action: Action{Type: ActionUnarchive, CardId: "unlabeled"},
+			err:    fmt.Errorf("card unlabeled does not have the service label"),
+		},
+		{
+			name:   "unknown card",
+			action: Action{Type: ActionSetDue, CardId: "missing"},
+			err:    fmt.Errorf("could not fetch card: card not found"),
+		},
+		{
+			name:    "service without label",
+			noLabel: true,
+			action:  Action{Type: ActionCreateCard, Name: "Drink water"},
+			err:     fmt.Errorf("service has no label to act on"),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &fakeActionClient{
+				boardId: "board",
+				lists:   []string{"l1", "l2"},
+				cards: map[string]entrello.Card{
+					"own":       {ID: "own", IDBoard: "board", IDLabels: []string{"other", "label"}},
+					"foreign":   {ID: "foreign", IDBoard: "other", IDLabels: []string{"label"}},
+					"unlabeled": {ID: "unlabeled", IDBoard: "board", IDLabels: []string{"other"}},
+				},
+			}
+
+			svc := service
+			if tc.noLabel {
+				svc.Label = ""
+			}
+
+			err := applyAction(tc.action, svc, client)
+			if err != nil || tc.err != nil {
+				if err == nil || tc.err == nil || err.Error() != tc.err.Error() {
+					t.Errorf("expected error to be %v, got '%v'", tc.err, err)
+				}
+				if len(client.applied) > 0 {
+					t.Errorf("wanted no changes to the board, got %v", client.applied)
+				}
+				return
+			}
+
+			if len(client.applied) != 1 || client.applied[0] != tc.applied {
+				t.Errorf("wanted '%s' to be applied, got %v", tc.applied, client.applied)
+			}
+		})
+	}
+}
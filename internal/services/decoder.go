@@ -0,0 +1,125 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/utkuufuk/entrello/pkg/trello"
+)
+
+var errBodyTooLarge = errors.New("response body exceeds the size limit")
+
+// itemError describes why a single item in a service response has been rejected
+type itemError struct {
+	index int
+	err   error
+}
+
+func (e itemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.index, e.err)
+}
+
+// limitedReader reads from the underlying reader until the limit is exceeded, after which
+// it returns errBodyTooLarge instead of silently truncating the body like io.LimitReader
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func newLimitedReader(r io.Reader, limit int64) *limitedReader {
+	return &limitedReader{r, limit}
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, errBodyTooLarge
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, errBodyTooLarge
+	}
+	return n, err
+}
+
+// decodeCards decodes either a JSON array or a stream of newline-delimited JSON cards one item
+// at a time, and validates each item separately so that the invalid items can be skipped and
+// reported without failing the whole batch. Fails if the response contains more than maxItems.
+func decodeCards(
+	r io.Reader,
+	ndjson bool,
+	strictSchema bool,
+	maxItems int,
+) (
+	cards []trello.Card,
+	invalid []itemError,
+	err error,
+) {
+	decoder := json.NewDecoder(r)
+
+	if !ndjson {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not decode cards: %w", err)
+		}
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			return nil, nil, fmt.Errorf("could not decode cards: expected a JSON array, got %v", token)
+		}
+	}
+
+	for i := 0; ; i++ {
+		if !ndjson && !decoder.More() {
+			break
+		}
+
+		var item json.RawMessage
+		err = decoder.Decode(&item)
+		if ndjson && err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not decode item %d: %w", i, err)
+		}
+
+		if i >= maxItems {
+			return nil, nil, fmt.Errorf("response contains more than %d items", maxItems)
+		}
+
+		card, err := decodeCard(item, strictSchema)
+		if err != nil {
+			invalid = append(invalid, itemError{i, err})
+			continue
+		}
+		cards = append(cards, card)
+	}
+
+	if !ndjson {
+		if _, err = decoder.Token(); err != nil {
+			return nil, nil, fmt.Errorf("could not decode cards: %w", err)
+		}
+	}
+	return cards, invalid, nil
+}
+
+// decodeCard decodes and validates a single card, rejecting unknown fields in strict schema mode
+func decodeCard(data []byte, strictSchema bool) (card trello.Card, err error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if strictSchema {
+		decoder.DisallowUnknownFields()
+	}
+
+	if err = decoder.Decode(&card); err != nil {
+		return nil, fmt.Errorf("could not decode card: %w", err)
+	}
+
+	if err = trello.ValidateCard(card); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestDecodeCards(t *testing.T) {
+	tt := []struct {
+		name         string
+		body         string
+		ndjson       bool
+		strictSchema bool
+		numCards     int
+		invalid      []string
+		err          error
+	}{
+		{
+			name:     "all valid",
+			body:     `[{"name": "a"}, {"name": "b", "desc": "desc", "due": "2022-04-01T00:00:00Z"}]`,
+			numCards: 2,
+		},
+		{
+			name:     "blank name and malformed due date are skipped",
+			body:     `[{"name": ""}, {"name": "b", "due": "tomorrow"}, {"name": "c"}]`,
+			numCards: 1,
+			invalid: []string{
+				"item 0: card name cannot be blank",
+				"item 1: could not decode card: ",
+			},
+		},
+		{
+			name:     "null item is skipped",
+			body:     `[null, {"name": "b"}]`,
+			numCards: 1,
+			invalid:  []string{"item 0: card cannot be null"},
+		},
+		{
+			name:     "unknown fields are allowed by default",
+			body:     `[{"name": "a", "title": "b"}]`,
+			numCards: 1,
+		},
+		{
+			name:         "unknown fields are rejected in strict schema mode",
+			body:         `[{"name": "a", "title": "b"}, {"name": "c"}]`,
+			strictSchema: true,
+			numCards:     1,
+			invalid:      []string{"item 0: could not decode card: json: unknown field"},
+		},
+		{
+			name:     "newline-delimited JSON",
+			body:     "{\"name\": \"a\"}\n{\"name\": \"\"}\n{\"name\": \"c\"}\n",
+			ndjson:   true,
+			numCards: 2,
+			invalid:  []string{"item 1: card name cannot be blank"},
+		},
+		{
+			name:   "empty newline-delimited JSON",
+			body:   "",
+			ndjson: true,
+		},
+		{
+			name: "too many items",
+			body: `[{"name": "a"}, {"name": "b"}, {"name": "c"}, {"name": "d"}]`,
+			err:  fmt.Errorf("response contains more than 3 items"),
+		},
+		{
+			name:   "too many newline-delimited items",
+			body:   "{\"name\": \"a\"}\n{\"name\": \"b\"}\n{\"name\": \"c\"}\n{\"name\": \"d\"}\n",
+			ndjson: true,
+			err:    fmt.Errorf("response contains more than 3 items"),
+		},
+		{
+			name: "body too large",
+			body: fmt.Sprintf(`[{"name": "%s"}]`, strings.Repeat("a", 100)),
+			err:  fmt.Errorf("could not decode item 0: response body exceeds the size limit"),
+		},
+		{
+			name: "not an array",
+			body: `{"name": "a"}`,
+			err:  fmt.Errorf("could not decode cards: expected a JSON array, got {"),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cards, invalid, err := decodeCards(
+				newLimitedReader(strings.NewReader(tc.body), 100),
+				tc.ndjson,
+				tc.strictSchema,
+				3,
+			)
+
+			if err != nil || tc.err != nil {
+				if err == nil || tc.err == nil || !strings.HasPrefix(err.Error(), tc.err.Error()) {
+					t.Errorf("expected error to be %v, got '%v'", tc.err, err)
+				}
+				return
+			}
+
+			if len(cards) != tc.numCards {
+				t.Errorf("wanted %d cards, got %d", tc.numCards, len(cards))
+			}
+
+			if len(invalid) != len(tc.invalid) {
+				t.Fatalf("wanted %d invalid items, got %d: %v", len(tc.invalid), len(invalid), invalid)
+			}
+			for i, e := range invalid {
+				if !strings.HasPrefix(e.Error(), tc.invalid[i]) {
+					t.Errorf("wanted invalid item error '%s', got '%s'", tc.invalid[i], e)
+				}
+			}
+		})
+	}
+}
+
+func TestLimitedReader(t *testing.T) {
+	tt := []struct {
+		name  string
+		body  string
+		limit int64
+		err   error
+	}{
+		{
+			name:  "below the limit",
+			body:  "abc",
+			limit: 4,
+		},
+		{
+			name:  "at the limit",
+			body:  "abcd",
+			limit: 4,
+		},
+		{
+			name:  "above the limit",
+			body:  "abcde",
+			limit: 4,
+			err:   errBodyTooLarge,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := ioutil.ReadAll(newLimitedReader(strings.NewReader(tc.body), tc.limit))
+			if err != tc.err {
+				t.Errorf("expected error to be %v, got '%v'", tc.err, err)
+			}
+			if err == nil && string(b) != tc.body {
+				t.Errorf("wanted body '%s', got '%s'", tc.body, b)
+			}
+		})
+	}
+}
+
+func TestParseContentType(t *testing.T) {
+	tt := []struct {
+		name   string
+		header string
+		want   string
+		err    error
+	}{
+		{
+			name:   "missing header defaults to JSON",
+			header: "",
+			want:   contentTypeJson,
+		},
+		{
+			name:   "JSON with charset",
+			header: "application/json; charset=utf-8",
+			want:   contentTypeJson,
+		},
+		{
+			name:   "NDJSON",
+			header: "application/x-ndjson",
+			want:   contentTypeNdjson,
+		},
+		{
+			name:   "unsupported content type",
+			header: "text/html; charset=utf-8",
+			err:    fmt.Errorf("unsupported content type: 'text/html'"),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseContentType(tc.header)
+
+			if err != nil || tc.err != nil {
+				if err == nil || tc.err == nil || err.Error() != tc.err.Error() {
+					t.Errorf("expected error to be %v, got '%v'", tc.err, err)
+				}
+				return
+			}
+
+			if got != tc.want {
+				t.Errorf("wanted content type '%s', got '%s'", tc.want, got)
+			}
+		})
+	}
+}
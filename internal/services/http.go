@@ -0,0 +1,140 @@
+package services
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/internal/logger"
+)
+
+// newHttpClient creates an HTTP client with the timeout, proxy and TLS settings of the given config
+func newHttpClient(cfg config.Http) (*http.Client, error) {
+	timeout := config.DefaultTimeout
+	if cfg.Timeout != "" {
+		t, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout '%s': %w", cfg.Timeout, err)
+		}
+		timeout = t
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.Proxy != "" {
+		proxyUrl, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL '%s': %w", cfg.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+
+	tlsConfig, err := newTlsConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &http.Client{Timeout: timeout, Transport: transport}, nil
+}
+
+// newTlsConfig creates a TLS config with the custom CA certificate and the client certificate
+// of the given config, if any. Returns nil if neither of them is configured.
+func newTlsConfig(cfg config.Http) (*tls.Config, error) {
+	if cfg.CaCert == "" && cfg.ClientCert == "" && cfg.ClientKey == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.CaCert != "" {
+		pem, err := ioutil.ReadFile(cfg.CaCert)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("could not parse CA certificate in %s", cfg.CaCert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCert != "" || cfg.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// doWithRetry sends the given request and retries it with exponential backoff up to the configured
+// number of times upon network errors and 5xx responses
+func doWithRetry(client *http.Client, req *http.Request, cfg config.Http) (*http.Response, error) {
+	backoff := config.DefaultBackoff
+	if cfg.Backoff != "" {
+		b, err := time.ParseDuration(cfg.Backoff)
+		if err != nil {
+			return nil, fmt.Errorf("invalid backoff '%s': %w", cfg.Backoff, err)
+		}
+		backoff = b
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("could not rewind request body: %w", err)
+			}
+			req.Body = body
+		}
+
+		resp, err := client.Do(req)
+		if attempt >= cfg.Retries || (err == nil && resp.StatusCode < http.StatusInternalServerError) {
+			return resp, err
+		}
+
+		if err != nil {
+			logger.Warn("%s %s failed, retrying in %v: %v", req.Method, req.URL.Host, backoff, err)
+		} else {
+			logger.Warn("%s %s returned %d, retrying in %v", req.Method, req.URL.Host, resp.StatusCode, backoff)
+			io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxErrorBodySize))
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// CheckEndpoint checks whether the endpoint of the given service is reachable within the given timeout
+// by making a HEAD request, where any response counts as reachable regardless of its status code
+func CheckEndpoint(service config.Service, timeout time.Duration) error {
+	client, err := newHttpClient(service.Http)
+	if err != nil {
+		return fmt.Errorf("could not create HTTP client: %w", err)
+	}
+	defer client.CloseIdleConnections()
+
+	client.Timeout = timeout
+	resp, err := client.Head(service.Endpoint)
+	if err != nil {
+		return fmt.Errorf("endpoint is not reachable: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/utkuufuk/entrello/internal/config"
+)
+
+func TestNewHttpClient(t *testing.T) {
+	tt := []struct {
+		name string
+		cfg  config.Http
+		err  error
+	}{
+		{
+			name: "defaults",
+			cfg:  config.Http{},
+		},
+		{
+			name: "custom timeout and proxy",
+			cfg:  config.Http{Timeout: "5s", Proxy: "http://proxy.local:3128"},
+		},
+		{
+			name: "invalid timeout",
+			cfg:  config.Http{Timeout: "5"},
+			err:  fmt.Errorf("invalid timeout '5': time: missing unit in duration \"5\""),
+		},
+		{
+			name: "missing CA certificate",
+			cfg:  config.Http{CaCert: "/nonexistent/ca.pem"},
+			err:  fmt.Errorf("could not read CA certificate: open /nonexistent/ca.pem: no such file or directory"),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newHttpClient(tc.cfg)
+			if err != nil || tc.err != nil {
+				if err == nil || tc.err == nil || err.Error() != tc.err.Error() {
+					t.Errorf("expected error to be %v, got '%v'", tc.err, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDoWithRetry(t *testing.T) {
+	tt := []struct {
+		name     string
+		statuses []int
+		retries  int
+		status   int
+		attempts int
+	}{
+		{
+			name:     "success without retries",
+			statuses: []int{http.StatusOK},
+			retries:  2,
+			status:   http.StatusOK,
+			attempts: 1,
+		},
+		{
+			name:     "success after retries",
+			statuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK},
+			retries:  2,
+			status:   http.StatusOK,
+			attempts: 3,
+		},
+		{
+			name:     "retries exhausted",
+			statuses: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			retries:  1,
+			status:   http.StatusBadGateway,
+			attempts: 2,
+		},
+		{
+			name:     "no retries upon 4xx",
+			statuses: []int{http.StatusNotFound, http.StatusOK},
+			retries:  2,
+			status:   http.StatusNotFound,
+			attempts: 1,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				if string(body) != "payload" {
+					t.Errorf("wanted request body 'payload', got '%s'", body)
+				}
+				w.WriteHeader(tc.statuses[attempts])
+				attempts++
+			}))
+			defer server.Close()
+
+			req, _ := http.NewRequest("POST", server.URL, bytes.NewBufferString("payload"))
+			cfg := config.Http{Retries: tc.retries, Backoff: "1ms"}
+			resp, err := doWithRetry(server.Client(), req, cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tc.status {
+				t.Errorf("wanted status %d, got %d", tc.status, resp.StatusCode)
+			}
+			if attempts != tc.attempts {
+				t.Errorf("wanted %d attempts, got %d", tc.attempts, attempts)
+			}
+		})
+	}
+}
+
+func TestDoWithRetryCancelled(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	cfg := config.Http{Retries: 5, Backoff: "1h"}
+	if _, err := doWithRetry(server.Client(), req, cfg); !errors.Is(err, context.Canceled) {
+		t.Fatalf("wanted context.Canceled, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("wanted 1 attempt, got %d", attempts)
+	}
+}
+
+func TestCheckEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	url := server.URL
+
+	if err := CheckEndpoint(config.Service{Endpoint: url}, time.Second); err != nil {
+		t.Errorf("wanted reachable endpoint, got error: %v", err)
+	}
+
+	server.Close()
+	if err := CheckEndpoint(config.Service{Endpoint: url}, time.Second); err == nil {
+		t.Errorf("wanted an error for an unreachable endpoint")
+	}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/internal/state"
+	"github.com/utkuufuk/entrello/pkg/trello"
+	"golang.org/x/exp/slices"
+)
+
+// delta represents the response of an incrementally polled service, containing the items added and
+// the names of the items removed since the given cursor, as well as an optional new cursor
+type delta struct {
+	Cursor  string            `json:"cursor"`
+	Added   []json.RawMessage `json:"added"`
+	Removed []string          `json:"removed"`
+}
+
+// fetchIncremental polls the given service with the cursor from the latest successful poll, merges
+// the received delta into the stored set of items, and returns the merged set of items along with
+// the invalid items in the delta. The cursor is only advanced if the delta contains no invalid items.
+func fetchIncremental(
+	ctx context.Context,
+	service config.Service,
+	store *state.Store,
+) (
+	cards []trello.Card,
+	invalid []itemError,
+	err error,
+) {
+	prev := store.Get(service.Label)
+	startedAt := time.Now().UTC()
+
+	var d delta
+	var added []trello.Card
+	err = fetch(ctx, service, prev.Cursor, func(body io.Reader, contentType string, maxItems int) error {
+		if contentType != contentTypeJson {
+			return fmt.Errorf("unsupported content type for incremental polling: '%s'", contentType)
+		}
+		d, added, invalid, err = decodeDelta(body, service.StrictSchema, maxItems)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cards = mergeDelta(prev.Cards, added, d.Removed)
+
+	// keep the previous cursor if any item has been rejected, so that the service sends the
+	// rejected items again in the next delta instead of them being lost
+	cursor := prev.Cursor
+	if len(invalid) == 0 {
+		cursor = d.Cursor
+		if cursor == "" {
+			cursor = startedAt.Format(time.RFC3339)
+		}
+	}
+
+	store.Set(service.Label, state.Service{Cursor: cursor, Cards: cards})
+	return cards, invalid, nil
+}
+
+// setCursor puts the cursor in the configured request header, or the configured query parameter
+// if no header is configured
+func setCursor(req *http.Request, cfg config.Incremental, cursor string) {
+	if cfg.Header != "" {
+		req.Header.Set(cfg.Header, cursor)
+		return
+	}
+
+	param := cfg.Param
+	if param == "" {
+		param = config.DefaultCursorParam
+	}
+
+	query := req.URL.Query()
+	query.Set(param, cursor)
+	req.URL.RawQuery = query.Encode()
+}
+
+// decodeDelta decodes a delta response and validates each of the added items separately.
+// Fails if the delta contains more than maxItems added items.
+func decodeDelta(
+	r io.Reader,
+	strictSchema bool,
+	maxItems int,
+) (
+	d delta,
+	added []trello.Card,
+	invalid []itemError,
+	err error,
+) {
+	if err = json.NewDecoder(r).Decode(&d); err != nil {
+		return d, nil, nil, fmt.Errorf("could not decode delta: %w", err)
+	}
+
+	if len(d.Added) > maxItems {
+		return d, nil, nil, fmt.Errorf("response contains more than %d items", maxItems)
+	}
+
+	for i, item := range d.Added {
+		card, err := decodeCard(item, strictSchema)
+		if err != nil {
+			invalid = append(invalid, itemError{i, err})
+			continue
+		}
+		added = append(added, card)
+	}
+	return d, added, invalid, nil
+}
+
+// mergeDelta applies the added items and the names of the removed items to the given set of items,
+// where an added item replaces any existing item with the same name
+func mergeDelta(cards []trello.Card, added []trello.Card, removed []string) []trello.Card {
+	drop := make(map[string]bool, len(added)+len(removed))
+	for _, name := range removed {
+		drop[name] = true
+	}
+	for _, card := range added {
+		drop[card.Name] = true
+	}
+
+	merged := make([]trello.Card, 0, len(cards)+len(added))
+	for _, card := range cards {
+		if !drop[card.Name] {
+			merged = append(merged, card)
+		}
+	}
+
+	for _, card := range added {
+		if !slices.Contains(removed, card.Name) {
+			merged = append(merged, card)
+		}
+	}
+	return merged
+}
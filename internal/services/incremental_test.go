@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adlio/trello"
+	"github.com/google/go-cmp/cmp"
+	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/internal/state"
+	entrello "github.com/utkuufuk/entrello/pkg/trello"
+)
+
+func TestMergeDelta(t *testing.T) {
+	tt := []struct {
+		name    string
+		cards   []string
+		added   []string
+		removed []string
+		merged  []string
+	}{
+		{
+			name:   "initial delta",
+			cards:  []string{},
+			added:  []string{"a", "b"},
+			merged: []string{"a", "b"},
+		},
+		{
+			name:    "added and removed items",
+			cards:   []string{"a", "b", "c"},
+			added:   []string{"d"},
+			removed: []string{"b"},
+			merged:  []string{"a", "c", "d"},
+		},
+		{
+			name:   "added item replaces existing item",
+			cards:  []string{"a", "b"},
+			added:  []string{"a"},
+			merged: []string{"b", "a"},
+		},
+		{
+			name:    "removing unknown item",
+			cards:   []string{"a"},
+			removed: []string{"x"},
+			merged:  []string{"a"},
+		},
+		{
+			name:    "item both added and removed",
+			cards:   []string{"a"},
+			added:   []string{"b"},
+			removed: []string{"b"},
+			merged:  []string{"a"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			merged := mergeDelta(newTestCards(tc.cards), newTestCards(tc.added), tc.removed)
+
+			if diff := cmp.Diff(cardNames(merged), tc.merged); diff != "" {
+				t.Errorf("merged cards diff: %s", diff)
+			}
+		})
+	}
+}
+
+func TestDecodeDelta(t *testing.T) {
+	body := `{"cursor": "c2", "added": [{"name": "a"}, {"name": ""}], "removed": ["b"]}`
+	d, added, invalid, err := decodeDelta(strings.NewReader(body), false, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Cursor != "c2" {
+		t.Errorf("wanted cursor 'c2', got '%s'", d.Cursor)
+	}
+	if len(added) != 1 || added[0].Name != "a" {
+		t.Errorf("wanted a single added card named 'a', got %v", added)
+	}
+	if len(invalid) != 1 || invalid[0].Error() != "item 1: card name cannot be blank" {
+		t.Errorf("wanted a single invalid item at index 1, got %v", invalid)
+	}
+	if diff := cmp.Diff(d.Removed, []string{"b"}); diff != "" {
+		t.Errorf("removed items diff: %s", diff)
+	}
+
+	if _, _, _, err = decodeDelta(strings.NewReader(body), false, 1); err == nil {
+		t.Errorf("expected error upon too many items")
+	}
+}
+
+func TestSetCursor(t *testing.T) {
+	tt := []struct {
+		name   string
+		cfg    config.Incremental
+		url    string
+		header string
+	}{
+		{
+			name: "default query parameter",
+			cfg:  config.Incremental{Enabled: true},
+			url:  "http://example.com/items?since=2022-04-01T00%3A00%3A00Z",
+		},
+		{
+			name: "custom query parameter",
+			cfg:  config.Incremental{Enabled: true, Param: "cursor"},
+			url:  "http://example.com/items?cursor=2022-04-01T00%3A00%3A00Z",
+		},
+		{
+			name:   "header",
+			cfg:    config.Incremental{Enabled: true, Header: "X-Since"},
+			url:    "http://example.com/items",
+			header: "2022-04-01T00:00:00Z",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "http://example.com/items", nil)
+			setCursor(req, tc.cfg, "2022-04-01T00:00:00Z")
+
+			if req.URL.String() != tc.url {
+				t.Errorf("wanted URL '%s', got '%s'", tc.url, req.URL)
+			}
+			if req.Header.Get("X-Since") != tc.header {
+				t.Errorf("wanted header '%s', got '%s'", tc.header, req.Header.Get("X-Since"))
+			}
+		})
+	}
+}
+
+func TestFetchIncremental(t *testing.T) {
+	steps := []struct {
+		name     string
+		cursor   string
+		response string
+		cards    []string
+		invalid  int
+		stored   string
+	}{
+		{
+			name:     "initial poll without cursor",
+			response: `{"cursor": "c1", "added": [{"name": "a"}, {"name": "b"}]}`,
+			cards:    []string{"a", "b"},
+			stored:   "c1",
+		},
+		{
+			name:     "delta with an invalid item keeps the cursor",
+			cursor:   "c1",
+			response: `{"cursor": "c2", "added": [{"name": "c"}, {"name": ""}], "removed": ["a"]}`,
+			cards:    []string{"b", "c"},
+			invalid:  1,
+			stored:   "c1",
+		},
+		{
+			name:     "delta is sent again once the item is fixed",
+			cursor:   "c1",
+			response: `{"cursor": "c2", "added": [{"name": "c"}, {"name": "d"}], "removed": ["a"]}`,
+			cards:    []string{"b", "c", "d"},
+			stored:   "c2",
+		},
+		{
+			name:     "delta without cursor",
+			cursor:   "c2",
+			response: `{"removed": ["b"]}`,
+			cards:    []string{"c", "d"},
+		},
+	}
+
+	step := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cursor := r.Header.Get("X-Since"); cursor != steps[step].cursor {
+			t.Errorf("%s: wanted cursor '%s', got '%s'", steps[step].name, steps[step].cursor, cursor)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, steps[step].response)
+	}))
+	defer server.Close()
+
+	service := config.Service{
+		Label:       "label",
+		Endpoint:    server.URL,
+		Incremental: config.Incremental{Enabled: true, Header: "X-Since"},
+	}
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+
+	for ; step < len(steps); step++ {
+		tc := steps[step]
+
+		// load the state persisted by the previous poll as the runner does
+		store, err := state.Load(stateFile)
+		if err != nil {
+			t.Fatalf("%s: could not load state: %v", tc.name, err)
+		}
+
+		cards, invalid, err := fetchIncremental(context.Background(), service, store)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if len(invalid) != tc.invalid {
+			t.Errorf("%s: wanted %d invalid items, got %d", tc.name, tc.invalid, len(invalid))
+		}
+		if diff := cmp.Diff(tc.cards, cardNames(cards)); diff != "" {
+			t.Errorf("%s: cards diff: %s", tc.name, diff)
+		}
+
+		if err = store.Save(); err != nil {
+			t.Fatalf("%s: could not save state: %v", tc.name, err)
+		}
+
+		saved, err := state.Load(stateFile)
+		if err != nil {
+			t.Fatalf("%s: could not reload state: %v", tc.name, err)
+		}
+		persisted := saved.Get(service.Label)
+		if diff := cmp.Diff(tc.cards, cardNames(persisted.Cards)); diff != "" {
+			t.Errorf("%s: persisted cards diff: %s", tc.name, diff)
+		}
+
+		if tc.stored == "" {
+			// the start time of the poll is stored when the service doesn't return a cursor
+			if _, err = time.Parse(time.RFC3339, persisted.Cursor); err != nil {
+				t.Errorf("%s: wanted a timestamp cursor, got '%s'", tc.name, persisted.Cursor)
+			}
+			continue
+		}
+		if persisted.Cursor != tc.stored {
+			t.Errorf("%s: wanted persisted cursor '%s', got '%s'", tc.name, tc.stored, persisted.Cursor)
+		}
+	}
+}
+
+func cardNames(cards []entrello.Card) []string {
+	names := make([]string, 0, len(cards))
+	for _, c := range cards {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func newTestCards(names []string) []entrello.Card {
+	cards := make([]entrello.Card, 0, len(names))
+	for _, name := range names {
+		cards = append(cards, &trello.Card{Name: name})
+	}
+	return cards
+}
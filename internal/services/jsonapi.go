@@ -0,0 +1,151 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/pkg/trello"
+)
+
+// mapJsonApiResponse decodes an arbitrary JSON API response and maps each of its items
+// to a Trello card according to the given mapping, skipping the items that cannot be mapped.
+// Fails if the response contains more than maxItems.
+func mapJsonApiResponse(
+	r io.Reader,
+	mapping config.Mapping,
+	maxItems int,
+) (
+	cards []trello.Card,
+	invalid []itemError,
+	err error,
+) {
+	if mapping.Name == "" {
+		return nil, nil, fmt.Errorf("mapping for card name cannot be blank")
+	}
+
+	// no-op for the mappings decoded from a config, whose templates have already been parsed
+	if err = mapping.ParseTemplates(); err != nil {
+		return nil, nil, err
+	}
+
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var root interface{}
+	if err = decoder.Decode(&root); err != nil {
+		return nil, nil, fmt.Errorf("could not decode JSON API response: %w", err)
+	}
+
+	value, err := selectValue(root, mapping.Items)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not select items: %w", err)
+	}
+
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("expected items at '%s' to be an array, got %T", mapping.Items, value)
+	}
+
+	if len(items) > maxItems {
+		return nil, nil, fmt.Errorf("response contains more than %d items", maxItems)
+	}
+
+	cards = make([]trello.Card, 0, len(items))
+	for i, item := range items {
+		card, err := mapItem(item, mapping)
+		if err != nil {
+			invalid = append(invalid, itemError{i, err})
+			continue
+		}
+		cards = append(cards, card)
+	}
+	return cards, invalid, nil
+}
+
+// mapItem maps a single JSON API item to a Trello card
+func mapItem(item interface{}, mapping config.Mapping) (card trello.Card, err error) {
+	name, err := render(item, mapping, mapping.Name)
+	if err != nil {
+		return card, fmt.Errorf("could not render name: %w", err)
+	}
+
+	description, err := render(item, mapping, mapping.Description)
+	if err != nil {
+		return card, fmt.Errorf("could not render description: %w", err)
+	}
+
+	due, err := render(item, mapping, mapping.Due)
+	if err != nil {
+		return card, fmt.Errorf("could not render due date: %w", err)
+	}
+
+	var dueDate *time.Time
+	if due != "" {
+		layout := mapping.DueLayout
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		d, err := time.Parse(layout, due)
+		if err != nil {
+			return card, fmt.Errorf("could not parse due date '%s': %w", due, err)
+		}
+		dueDate = &d
+	}
+
+	return trello.NewCard(name, description, dueDate)
+}
+
+// render evaluates the given expression of the mapping against the item, where the expression is
+// either a parsed Go template or a dot-separated selector. Returns an empty string for an empty
+// expression.
+func render(item interface{}, mapping config.Mapping, expr string) (string, error) {
+	if expr == "" {
+		return "", nil
+	}
+
+	tmpl, ok := mapping.Template(expr)
+	if !ok {
+		value, err := selectValue(item, expr)
+		if err != nil || value == nil {
+			return "", err
+		}
+		return fmt.Sprint(value), nil
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, item); err != nil {
+		return "", fmt.Errorf("could not execute template '%s': %w", expr, err)
+	}
+	return buf.String(), nil
+}
+
+// selectValue walks the given JSON value along a dot-separated selector such as "data.items"
+// or "$.data.items.0", and returns the value at the end of the path
+func selectValue(value interface{}, selector string) (interface{}, error) {
+	selector = strings.TrimPrefix(strings.TrimPrefix(selector, "$"), ".")
+	if selector == "" {
+		return value, nil
+	}
+
+	for _, key := range strings.Split(selector, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[key]
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil, fmt.Errorf("invalid array index '%s' in selector '%s'", key, selector)
+			}
+			value = v[idx]
+		default:
+			return nil, nil
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/utkuufuk/entrello/internal/config"
+)
+
+func TestMapJsonApiResponse(t *testing.T) {
+	due := time.Date(2022, time.Month(4), 1, 9, 30, 0, 0, time.UTC)
+	tt := []struct {
+		name    string
+		body    string
+		mapping config.Mapping
+		names   []string
+		descs   []string
+		due     *time.Time
+		invalid []string
+		err     error
+	}{
+		{
+			name:    "root array with selectors",
+			body:    `[{"title": "a"}, {"title": "b"}]`,
+			mapping: config.Mapping{Name: "title"},
+			names:   []string{"a", "b"},
+			descs:   []string{"", ""},
+		},
+		{
+			name:    "nested items with templates",
+			body:    `{"data": {"issues": [{"number": 12, "title": "fix it", "url": "http://x"}]}}`,
+			mapping: config.Mapping{Items: "$.data.issues", Name: "#{{.number}} {{.title}}", Description: "url"},
+			names:   []string{"#12 fix it"},
+			descs:   []string{"http://x"},
+		},
+		{
+			name:    "array index selectors",
+			body:    `{"data": [{"tags": ["x", "y"]}, {"tags": ["z"]}]}`,
+			mapping: config.Mapping{Items: "data", Name: "tags.0", Description: "tags.1"},
+			names:   []string{"x"},
+			descs:   []string{"y"},
+			invalid: []string{"item 1: could not render description: invalid array index '1' in selector 'tags.1'"},
+		},
+		{
+			name:    "item without a field of the template is skipped",
+			body:    `[{"number": 1, "title": "a"}, {"number": 2}]`,
+			mapping: config.Mapping{Name: "#{{.number}} {{.title}}"},
+			names:   []string{"#1 a"},
+			descs:   []string{""},
+			invalid: []string{
+				`item 1: could not render name: could not execute template '#{{.number}} {{.title}}': ` +
+					`template: name:1:15: executing "name" at <.title>: map has no entry for key "title"`,
+			},
+		},
+		{
+			name:    "invalid template",
+			body:    `[{"title": "a"}]`,
+			mapping: config.Mapping{Name: "{{.title"},
+			err:     fmt.Errorf(`invalid name template in mapping: template: name:1: unclosed action`),
+		},
+		{
+			name:    "due date with custom layout",
+			body:    `[{"title": "a", "deadline": "2022-04-01 09:30"}]`,
+			mapping: config.Mapping{Name: "title", Due: "deadline", DueLayout: "2006-01-02 15:04"},
+			names:   []string{"a"},
+			descs:   []string{""},
+			due:     &due,
+		},
+		{
+			name:    "missing name mapping",
+			body:    `[{"title": "a"}]`,
+			mapping: config.Mapping{},
+			err:     fmt.Errorf("mapping for card name cannot be blank"),
+		},
+		{
+			name:    "items are not an array",
+			body:    `{"data": {"title": "a"}}`,
+			mapping: config.Mapping{Items: "data", Name: "title"},
+			err:     fmt.Errorf("expected items at 'data' to be an array, got map[string]interface {}"),
+		},
+		{
+			name:    "too many items",
+			body:    `[{"title": "a"}, {"title": "b"}, {"title": "c"}, {"title": "d"}]`,
+			mapping: config.Mapping{Name: "title"},
+			err:     fmt.Errorf("response contains more than 3 items"),
+		},
+		{
+			name:    "blank card name is skipped",
+			body:    `[{"title": ""}, {"title": "b"}]`,
+			mapping: config.Mapping{Name: "title"},
+			names:   []string{"b"},
+			descs:   []string{""},
+			invalid: []string{"item 0: card name cannot be blank"},
+		},
+		{
+			name:    "malformed due date is skipped",
+			body:    `[{"title": "a", "due": "tomorrow"}]`,
+			mapping: config.Mapping{Name: "title", Due: "due"},
+			names:   []string{},
+			invalid: []string{
+				"item 0: could not parse due date 'tomorrow': " +
+					`parsing time "tomorrow" as "2006-01-02T15:04:05Z07:00": cannot parse "tomorrow" as "2006"`,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cards, invalid, err := mapJsonApiResponse(strings.NewReader(tc.body), tc.mapping, 3)
+
+			if err != nil || tc.err != nil {
+				if err == nil || tc.err == nil || err.Error() != tc.err.Error() {
+					t.Errorf("expected error to be %v, got '%v'", tc.err, err)
+				}
+				return
+			}
+
+			if len(invalid) != len(tc.invalid) {
+				t.Fatalf("wanted %d invalid items, got %d", len(tc.invalid), len(invalid))
+			}
+			for i, e := range invalid {
+				if e.Error() != tc.invalid[i] {
+					t.Errorf("wanted invalid item error '%s', got '%s'", tc.invalid[i], e)
+				}
+			}
+
+			if len(cards) != len(tc.names) {
+				t.Fatalf("wanted %d cards, got %d", len(tc.names), len(cards))
+			}
+
+			for i, card := range cards {
+				if card.Name != tc.names[i] {
+					t.Errorf("wanted card name '%s', got '%s'", tc.names[i], card.Name)
+				}
+				if card.Desc != tc.descs[i] {
+					t.Errorf("wanted card description '%s', got '%s'", tc.descs[i], card.Desc)
+				}
+				if (tc.due == nil) != (card.Due == nil) || (tc.due != nil && !tc.due.Equal(*card.Due)) {
+					t.Errorf("wanted due date %v, got %v", tc.due, card.Due)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/internal/metrics"
+)
+
+const (
+	outcomeSuccess = "success"
+	outcomeFailure = "failure"
+)
+
+var (
+	pollsTotal = metrics.NewCounter(
+		"entrello_polls_total",
+		"Number of service polls by outcome.",
+		"service", "outcome",
+	)
+	cardsCreatedTotal = metrics.NewCounter(
+		"entrello_cards_created_total",
+		"Number of Trello cards created upon synchronization.",
+		"service",
+	)
+	cardsDeletedTotal = metrics.NewCounter(
+		"entrello_cards_deleted_total",
+		"Number of stale Trello cards deleted upon synchronization.",
+		"service",
+	)
+	serviceRequestDuration = metrics.NewHistogram(
+		"entrello_service_request_duration_seconds",
+		"Latency of the requests made to the services, including retries.",
+		metrics.DefaultBuckets,
+		"service", "method",
+	)
+	deliveriesTotal = metrics.NewCounter(
+		"entrello_notification_deliveries_total",
+		"Number of notification delivery attempts by outcome.",
+		"service", "outcome",
+	)
+)
+
+// serviceLabel identifies a service in the metrics, where the endpoint is never used
+// since it may contain credentials
+func serviceLabel(service config.Service) string {
+	if service.Name != "" {
+		return service.Name
+	}
+	return service.Label
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return outcomeFailure
+	}
+	return outcomeSuccess
+}
@@ -1,18 +1,28 @@
 package services
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
-	"sync"
 	"time"
 
 	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/internal/history"
 	"github.com/utkuufuk/entrello/internal/logger"
+	"github.com/utkuufuk/entrello/internal/state"
 	"github.com/utkuufuk/entrello/pkg/trello"
 )
 
+const (
+	contentTypeJson   = "application/json"
+	contentTypeNdjson = "application/x-ndjson"
+
+	maxErrorBodySize = 4096
+)
+
 // getServicesToPoll returns a slice of services to poll & another slice of relevant service labels
 func getServicesToPoll(
 	serviceArr []config.Service,
@@ -72,59 +82,217 @@ func shouldPoll(service config.Service, date time.Time) (bool, error) {
 	return false, fmt.Errorf("unrecognized service period type: '%s'", service.Period.Type)
 }
 
+// Report represents the outcome of polling a single service, where the names of the created and deleted
+// cards are listed even in dry-run mode, and any failures are listed in errors
+type Report struct {
+	Service string   `json:"service"`
+	Created []string `json:"created"`
+	Deleted []string `json:"deleted"`
+	Invalid int      `json:"invalid"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
 // poll polls the given service and creates Trello cards for each item unless
-// a corresponding card already exists, also deletes the stale cards if strict mode is enabled
-func poll(service config.Service, client trello.Client, wg *sync.WaitGroup) {
-	defer wg.Done()
+// a corresponding card already exists, also deletes the stale cards if strict mode is enabled.
+// In dry-run mode, the changes are only logged instead of being applied to the board.
+func poll(ctx context.Context, service config.Service, client trello.Client, store *state.Store, dryRun bool) Report {
+	report := Report{Service: service.Name, Created: make([]string, 0), Deleted: make([]string, 0)}
+	start := time.Now().UTC()
+	defer func() {
+		history.Default.AddRun(history.Run{
+			Service: serviceLabel(service),
+			Time:    start,
+			DryRun:  dryRun,
+			Created: len(report.Created),
+			Deleted: len(report.Deleted),
+			Invalid: report.Invalid,
+			Errors:  report.Errors,
+		})
 
-	req, err := http.NewRequest("GET", service.Endpoint, nil)
-	if err != nil {
-		logger.Error("could not create GET request to service '%s' endpoint: %v", service.Name, err)
-	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("X-Api-Key", service.Secret)
+		if len(report.Errors) > 0 {
+			pollsTotal.Inc(serviceLabel(service), outcomeFailure)
+			return
+		}
+		pollsTotal.Inc(serviceLabel(service), outcomeSuccess)
+	}()
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		logger.Error("could not make GET request to service '%s' endpoint: %v", service.Name, err)
-		return
+	var cards []trello.Card
+	var invalid []itemError
+	var err error
+	if service.Incremental.Enabled {
+		cards, invalid, err = fetchIncremental(ctx, service, store)
+	} else {
+		cards, invalid, err = fetchCards(ctx, service)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, err := ioutil.ReadAll(resp.Body)
-		msg := string(body)
-		if err != nil {
-			msg = err.Error()
-		}
-		logger.Error("could not retrieve cards from service '%s': %v", service.Name, msg)
-		return
+	if err != nil {
+		logger.Error("could not retrieve cards from service '%s': %v", service.Name, err)
+		report.Errors = append(report.Errors, fmt.Sprintf("could not retrieve cards: %v", err))
+		return report
 	}
 
-	var cards []trello.Card
-	if err = json.NewDecoder(resp.Body).Decode(&cards); err != nil {
-		logger.Error("could not decode cards received from service '%s': %v", service.Name, err)
-		return
+	report.Invalid = len(invalid)
+	for _, e := range invalid {
+		logger.Warn("skipping invalid %v received from service '%s'", e, service.Name)
 	}
 
 	new, stale := client.FilterNewAndStale(cards, service.Label)
 	for _, c := range new {
+		if ctx.Err() != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("aborted: %v", ctx.Err()))
+			return report
+		}
+		if dryRun {
+			logger.Info("would create new card: %s", c.Name)
+			recordCard(history.EventCreated, service, c, true)
+			report.Created = append(report.Created, c.Name)
+			continue
+		}
 		if err := client.CreateCard(c, service.Label, service.List); err != nil {
 			logger.Error("could not create Trello card: %v", err)
+			report.Errors = append(report.Errors, fmt.Sprintf("could not create card '%s': %v", c.Name, err))
 			continue
 		}
 		logger.Info("created new card: %s", c.Name)
+		cardsCreatedTotal.Inc(serviceLabel(service))
+		recordCard(history.EventCreated, service, c, false)
+		report.Created = append(report.Created, c.Name)
 	}
 
 	if !service.Strict {
-		return
+		return report
 	}
 
 	for _, c := range stale {
+		if ctx.Err() != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("aborted: %v", ctx.Err()))
+			return report
+		}
+		if dryRun {
+			logger.Info("would delete stale card: %s", c.Name)
+			recordCard(history.EventDeleted, service, c, true)
+			report.Deleted = append(report.Deleted, c.Name)
+			continue
+		}
 		if err := client.DeleteCard(c); err != nil {
 			logger.Error("could not delete Trello card: %v", err)
+			report.Errors = append(report.Errors, fmt.Sprintf("could not delete card '%s': %v", c.Name, err))
 			continue
 		}
 		logger.Info("deleted stale card: %s", c.Name)
+		cardsDeletedTotal.Inc(serviceLabel(service))
+		recordCard(history.EventDeleted, service, c, false)
+		report.Deleted = append(report.Deleted, c.Name)
+	}
+	return report
+}
+
+// recordCard records the creation or deletion of the given card in the history
+func recordCard(eventType string, service config.Service, card trello.Card, dryRun bool) {
+	history.Default.AddEvent(history.Event{
+		Type:    eventType,
+		Service: serviceLabel(service),
+		Card:    card.Name,
+		DryRun:  dryRun,
+		Time:    time.Now().UTC(),
+	})
+}
+
+// fetchCards makes a GET request to the service endpoint and returns the valid cards in the response
+// along with the invalid items, mapping the response items to cards first if the service is a JSON
+// API source. Fails if the response body or the number of items exceeds the service limits.
+func fetchCards(ctx context.Context, service config.Service) (cards []trello.Card, invalid []itemError, err error) {
+	err = fetch(ctx, service, "", func(body io.Reader, contentType string, maxItems int) error {
+		switch service.Type {
+		case config.ServiceTypeDefault:
+			cards, invalid, err = decodeCards(body, contentType == contentTypeNdjson, service.StrictSchema, maxItems)
+			return err
+
+		case config.ServiceTypeJsonApi:
+			if contentType != contentTypeJson {
+				return fmt.Errorf("unsupported content type for JSON API source: '%s'", contentType)
+			}
+			cards, invalid, err = mapJsonApiResponse(body, service.Mapping, maxItems)
+			return err
+		}
+		return fmt.Errorf("unrecognized service type: '%s'", service.Type)
+	})
+	return cards, invalid, err
+}
+
+// fetch makes a GET request to the service endpoint, passing the given cursor if it's not empty,
+// and calls handle with the size-limited response body upon a successful response
+func fetch(
+	ctx context.Context,
+	service config.Service,
+	cursor string,
+	handle func(body io.Reader, contentType string, maxItems int) error,
+) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", service.Endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("could not create GET request to endpoint: %w", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Accept", fmt.Sprintf("%s, %s", contentTypeJson, contentTypeNdjson))
+	req.Header.Add("X-Api-Key", service.Secret)
+	if cursor != "" {
+		setCursor(req, service.Incremental, cursor)
+	}
+
+	client, err := newHttpClient(service.Http)
+	if err != nil {
+		return fmt.Errorf("could not create HTTP client: %w", err)
+	}
+	defer client.CloseIdleConnections()
+
+	start := time.Now()
+	resp, err := doWithRetry(client, req, service.Http)
+	serviceRequestDuration.Since(start, serviceLabel(service), http.MethodGet)
+	if err != nil {
+		return fmt.Errorf("could not make GET request to endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		msg := string(b)
+		if err != nil {
+			msg = err.Error()
+		}
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, msg)
+	}
+
+	contentType, err := parseContentType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+
+	maxBodySize := service.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = config.DefaultMaxBodySize
+	}
+
+	maxItems := service.MaxItems
+	if maxItems <= 0 {
+		maxItems = config.DefaultMaxItems
+	}
+
+	return handle(newLimitedReader(resp.Body, maxBodySize), contentType, maxItems)
+}
+
+// parseContentType returns the media type of the given Content-Type header, assuming JSON if the
+// header is missing, and fails if the media type is neither JSON nor NDJSON
+func parseContentType(header string) (string, error) {
+	if header == "" {
+		return contentTypeJson, nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return "", fmt.Errorf("could not parse content type '%s': %w", header, err)
+	}
+
+	if mediaType != contentTypeJson && mediaType != contentTypeNdjson {
+		return "", fmt.Errorf("unsupported content type: '%s'", mediaType)
 	}
+	return mediaType, nil
 }
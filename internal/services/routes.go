@@ -0,0 +1,65 @@
+package services
+
+import (
+	"regexp"
+
+	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/pkg/trello"
+	"golang.org/x/exp/slices"
+)
+
+// isRouted checks if the given card and event match any of the service routes, where services
+// without routes are routed the cards with their own label
+func isRouted(service config.Service, card trello.Card, event trello.Event) bool {
+	routes := service.Routes
+	if len(routes) == 0 {
+		routes = []config.Route{{Label: service.Label}}
+	}
+
+	for _, route := range routes {
+		if matchesRoute(route, card, event) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesRoute checks if the given card and event match all the non-empty criteria of the route,
+// where a route without any criteria matches nothing
+func matchesRoute(route config.Route, card trello.Card, event trello.Event) bool {
+	if route.Label == "" && route.List == "" && route.Board == "" && route.Name == "" {
+		return false
+	}
+
+	if route.Label != "" && route.Label != event.LabelId && !hasLabel(card, route.Label) {
+		return false
+	}
+
+	if route.List != "" && route.List != card.IDList && route.List != event.ListId {
+		return false
+	}
+
+	if route.Board != "" && route.Board != card.IDBoard && route.Board != event.BoardId {
+		return false
+	}
+
+	if route.Name != "" {
+		re, err := regexp.Compile(route.Name)
+		if err != nil || !re.MatchString(card.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasLabel(card trello.Card, labelId string) bool {
+	if slices.Contains(card.IDLabels, labelId) {
+		return true
+	}
+	for _, label := range card.Labels {
+		if label.ID == labelId {
+			return true
+		}
+	}
+	return false
+}
@@ -2,76 +2,338 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/internal/history"
+	"github.com/utkuufuk/entrello/internal/logger"
+	"github.com/utkuufuk/entrello/internal/outbox"
+	"github.com/utkuufuk/entrello/internal/state"
 	"github.com/utkuufuk/entrello/pkg/trello"
 	"golang.org/x/exp/slices"
 )
 
-// Poll polls any number of configured services that should be polled at the given time instant.
-func Poll(cfg config.RunnerConfig) error {
+// ErrServiceNotFound is returned when a service with the given name is not configured
+var ErrServiceNotFound = errors.New("service not found")
+
+// ErrSyncInProgress is returned when another sync is already in progress for the same board
+var ErrSyncInProgress = errors.New("sync already in progress for the board")
+
+// syncing holds the IDs of the boards that are being synchronized, so that overlapping syncs
+// within the same process don't create duplicate cards
+var syncing = struct {
+	sync.Mutex
+	boards map[string]bool
+}{boards: make(map[string]bool)}
+
+// Poll polls any number of configured services that should be polled at the given time instant,
+// and returns a report for each polled service. In dry-run mode, neither the board nor the stored
+// state is modified.
+func Poll(ctx context.Context, cfg config.RunnerConfig) ([]Report, error) {
+	return PollWithProgress(ctx, cfg, nil, nil)
+}
+
+// PollWithProgress is like Poll, but synchronizes the board under the given claim, if any, which is
+// released once the sync is finished. Also calls the given progress function, if any, with the number
+// of services that have been polled so far and the total number of services to poll.
+func PollWithProgress(
+	ctx context.Context,
+	cfg config.RunnerConfig,
+	claim *Claim,
+	progress func(done, total int),
+) ([]Report, error) {
+	defer claim.Release()
+
 	loc, err := time.LoadLocation(cfg.TimezoneLocation)
 	if err != nil {
-		return fmt.Errorf("invalid timezone location: %v", loc)
+		return nil, fmt.Errorf("invalid timezone location: %v", loc)
 	}
 
 	services, labels, err := getServicesToPoll(cfg.Services, time.Now().In(loc))
 	if err != nil {
-		return fmt.Errorf("failed to get services to poll: %w", err)
+		return nil, fmt.Errorf("failed to get services to poll: %w", err)
+	}
+	return pollServices(ctx, cfg, claim, services, labels, progress)
+}
+
+// PollService polls the configured service with the given name regardless of its period,
+// loading only the cards with the service label from the board
+func PollService(ctx context.Context, cfg config.RunnerConfig, name string) (Report, error) {
+	for _, service := range cfg.Services {
+		if service.Name != name {
+			continue
+		}
+
+		reports, err := pollServices(ctx, cfg, nil, []config.Service{service}, []string{service.Label}, nil)
+		if err != nil {
+			return Report{}, err
+		}
+		return reports[0], nil
 	}
+	return Report{}, ErrServiceNotFound
+}
+
+// pollServices polls the given services concurrently, after loading the existing cards
+// with the given labels from the board. Claims the board for the duration of the sync unless the caller
+// already holds a claim, and fails with ErrSyncInProgress if the board is already being synchronized.
+func pollServices(
+	ctx context.Context,
+	cfg config.RunnerConfig,
+	claim *Claim,
+	services []config.Service,
+	labels []string,
+	progress func(done, total int),
+) ([]Report, error) {
 	if len(services) == 0 {
-		return nil
+		return []Report{}, nil
 	}
 
-	client := trello.NewClient(cfg.Trello)
+	if claim == nil {
+		var err error
+		if claim, err = ClaimBoard(cfg.Trello.BoardId); err != nil {
+			return nil, err
+		}
+		defer claim.Release()
+	}
+
+	stateFile := cfg.StateFile
+	if stateFile == "" {
+		stateFile = config.DefaultStateFile
+	}
+
+	store, err := state.Load(stateFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load state: %w", err)
+	}
+
+	client := trello.NewClient(cfg.Trello).WithContext(ctx)
 
 	if err := client.LoadBoard(labels); err != nil {
-		return fmt.Errorf("Could not load existing cards from the board: %w", err)
+		err = fmt.Errorf("Could not load existing cards from the board: %w", err)
+		for _, service := range services {
+			history.Default.AddRun(history.Run{
+				Service: serviceLabel(service),
+				Time:    time.Now().UTC(),
+				DryRun:  cfg.DryRun,
+				Errors:  []string{err.Error()},
+			})
+		}
+		return nil, err
+	}
+
+	if progress != nil {
+		progress(0, len(services))
 	}
 
+	reports := make([]Report, len(services))
+	done := 0
+	var mu sync.Mutex
 	var wg sync.WaitGroup
 	wg.Add(len(services))
-	for _, src := range services {
-		go poll(src, client, &wg)
+	for i, src := range services {
+		go func(i int, src config.Service) {
+			defer wg.Done()
+			reports[i] = poll(ctx, src, client, store, cfg.DryRun)
+
+			if progress != nil {
+				mu.Lock()
+				defer mu.Unlock()
+				done++
+				progress(done, len(services))
+			}
+		}(i, src)
 	}
 	wg.Wait()
 
-	return nil
+	if cfg.DryRun {
+		return reports, nil
+	}
+
+	if err = store.Save(); err != nil {
+		return reports, fmt.Errorf("could not save state: %w", err)
+	}
+	return reports, nil
 }
 
-// Notify notifies any number of configured services with the latest state of the
-// given archived Trello card.
-func Notify(card trello.Card, services []config.Service) error {
-	labelIds := make([]string, 0)
-	for _, label := range card.Labels {
-		labelIds = append(labelIds, label.ID)
+// Claim represents the exclusive right to synchronize a board within the process
+type Claim struct {
+	boardId string
+	once    sync.Once
+}
+
+// ClaimBoard marks the board as being synchronized, failing with ErrSyncInProgress if it already is,
+// so that a sync can be rejected before it's started in the background
+func ClaimBoard(boardId string) (*Claim, error) {
+	syncing.Lock()
+	defer syncing.Unlock()
+
+	if syncing.boards[boardId] {
+		return nil, ErrSyncInProgress
 	}
+	syncing.boards[boardId] = true
+	return &Claim{boardId: boardId}, nil
+}
 
-	for _, service := range services {
-		if slices.Contains(labelIds, service.Label) {
-			postBody, err := json.Marshal(card)
-			if err != nil {
-				return fmt.Errorf("could not marshal archived card: %w", err)
-			}
+// Release releases the board, where releasing a nil or already released claim has no effect
+func (c *Claim) Release() {
+	if c == nil {
+		return
+	}
+	c.once.Do(func() {
+		syncing.Lock()
+		defer syncing.Unlock()
+		delete(syncing.boards, c.boardId)
+	})
+}
 
-			req, err := http.NewRequest("POST", service.Endpoint, bytes.NewBuffer(postBody))
-			if err != nil {
-				return fmt.Errorf("could not create POST request to %s: %w", service.Endpoint, err)
-			}
-			req.Header.Add("Content-Type", "application/json")
-			req.Header.Add("X-Api-Key", service.Secret)
+// Result represents the outcome of notifying a single service
+type Result struct {
+	Service config.Service
+	Err     error
+}
 
-			resp, err := http.DefaultClient.Do(req)
-			if err != nil {
-				return fmt.Errorf("could not post archived card data to %s: %w", service.Endpoint, err)
-			}
-			defer resp.Body.Close()
+// Notify enqueues a notification in the outbox for each configured service that is subscribed to the
+// given event and whose routes match the card, containing the latest state of the given Trello card,
+// wrapped in an event envelope if the service has opted in. The notifications are delivered later on
+// by the outbox worker. Returns a result for each matching service, where a failure for one service
+// does not affect the others.
+func Notify(
+	ctx context.Context,
+	card trello.Card,
+	event trello.Event,
+	services []config.Service,
+	box *outbox.Outbox,
+) []Result {
+	results := make([]Result, 0)
+	for _, service := range services {
+		if !isRouted(service, card, event) || !isSubscribed(service, event.Type) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			results = append(results, Result{service, err})
+			continue
 		}
+		results = append(results, Result{service, enqueue(card, event, service, box)})
+	}
+	return results
+}
+
+// enqueue enqueues a notification of the given event for a single service
+func enqueue(card trello.Card, event trello.Event, service config.Service, box *outbox.Outbox) error {
+	var payload interface{} = card
+	if service.Envelope {
+		payload = trello.NewEventEnvelope(event, card)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("could not marshal card: %w", err)
+	}
+
+	if _, err = box.Enqueue(service.Key(), event.Type, event.ActionId, body); err != nil {
+		return fmt.Errorf("could not enqueue notification: %w", err)
 	}
 	return nil
 }
+
+// Deliver posts the given outbox message to the given service, i.e. the currently configured service
+// that the message refers to, and fails unless the service responds with a 2xx status code. If the
+// service responds with a JSON body listing follow-up actions, the actions are applied through
+// the given Trello client.
+func Deliver(
+	ctx context.Context,
+	msg outbox.Message,
+	service config.Service,
+	client trello.Client,
+) (err error) {
+	defer func() { deliveriesTotal.Inc(serviceLabel(service), outcome(err)) }()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", service.Endpoint, bytes.NewBuffer(msg.Body))
+	if err != nil {
+		return fmt.Errorf("could not create POST request to %s: %w", service.Endpoint, err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("X-Api-Key", service.Secret)
+	req.Header.Add("X-Entrello-Event", msg.Event)
+	req.Header.Add("Idempotency-Key", msg.IdempotencyKey)
+
+	httpClient, err := newHttpClient(service.Http)
+	if err != nil {
+		return fmt.Errorf("could not create HTTP client for %s: %w", service.Endpoint, err)
+	}
+	defer httpClient.CloseIdleConnections()
+
+	start := time.Now()
+	resp, err := doWithRetry(httpClient, req, service.Http)
+	serviceRequestDuration.Since(start, serviceLabel(service), http.MethodPost)
+	if err != nil {
+		return fmt.Errorf("could not post card data to %s: %w", service.Endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return fmt.Errorf("unexpected status %d from %s: %s", resp.StatusCode, service.Endpoint, b)
+	}
+
+	history.Default.AddEvent(history.Event{
+		Type:    history.EventNotified,
+		Service: serviceLabel(service),
+		Card:    notifiedCard(msg.Body),
+		Detail:  msg.Event,
+		Time:    time.Now().UTC(),
+	})
+
+	// the notification has been delivered at this point, so the follow-up actions are best-effort
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != contentTypeJson {
+		return nil
+	}
+
+	actions, err := decodeActions(newLimitedReader(resp.Body, config.DefaultMaxBodySize))
+	if err != nil {
+		logger.Warn("Ignoring follow-up actions of service %s: %v", service.Endpoint, err)
+		return nil
+	}
+
+	for _, err := range applyActions(actions, service, client.WithContext(ctx)) {
+		logger.Error("Service %s: %v", service.Endpoint, err)
+	}
+	return nil
+}
+
+// notifiedCard returns the name of the card in the given notification body, which consists of either
+// the card itself or an event envelope, or an empty string if the body can't be decoded
+func notifiedCard(body []byte) string {
+	var payload struct {
+		Name string `json:"name"`
+		Card struct {
+			Name string `json:"name"`
+		} `json:"card"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+
+	if payload.Card.Name != "" {
+		return payload.Card.Name
+	}
+	return payload.Name
+}
+
+// isSubscribed checks if the service is subscribed to the given event type,
+// where services without any subscriptions are subscribed to archived card events only
+func isSubscribed(service config.Service, eventType string) bool {
+	if len(service.Events) == 0 {
+		return eventType == trello.EventCardArchived
+	}
+	return slices.Contains(service.Events, eventType)
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adlio/trello"
+	"github.com/utkuufuk/entrello/internal/config"
+	"github.com/utkuufuk/entrello/internal/history"
+	"github.com/utkuufuk/entrello/internal/outbox"
+	entrello "github.com/utkuufuk/entrello/pkg/trello"
+)
+
+func TestNotify(t *testing.T) {
+	tt := []struct {
+		name      string
+		event     entrello.Event
+		services  []config.Service
+		endpoints []string
+	}{
+		{
+			name:  "archived card notifies services with matching labels",
+			event: entrello.Event{Type: entrello.EventCardArchived},
+			services: []config.Service{
+				{Label: "a", Endpoint: "http://a"},
+				{Label: "b", Endpoint: "http://b"},
+				{Label: "c", Endpoint: "http://c"},
+			},
+			endpoints: []string{"http://a", "http://b"},
+		},
+		{
+			name:  "services without subscriptions are only notified of archived cards",
+			event: entrello.Event{Type: entrello.EventCardMoved},
+			services: []config.Service{
+				{Label: "a", Endpoint: "http://a"},
+				{Label: "b", Endpoint: "http://b", Events: []string{entrello.EventCardMoved}},
+			},
+			endpoints: []string{"http://b"},
+		},
+		{
+			name:  "removed label matches the service label",
+			event: entrello.Event{Type: entrello.EventLabelRemoved, LabelId: "c"},
+			services: []config.Service{
+				{Label: "c", Endpoint: "http://c", Events: []string{entrello.EventLabelRemoved}},
+			},
+			endpoints: []string{"http://c"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			box, err := outbox.New(t.TempDir(), 1, 0)
+			if err != nil {
+				t.Fatalf("could not create outbox: %v", err)
+			}
+
+			card := &trello.Card{ID: "card", Labels: []*trello.Label{{ID: "a"}, {ID: "b"}}}
+			results := Notify(context.Background(), card, tc.event, tc.services, box)
+
+			if len(results) != len(tc.endpoints) {
+				t.Fatalf("wanted %d results, got %d", len(tc.endpoints), len(results))
+			}
+			for i, result := range results {
+				if result.Err != nil {
+					t.Errorf("unexpected error for %s: %v", result.Service.Endpoint, result.Err)
+				}
+				if result.Service.Endpoint != tc.endpoints[i] {
+					t.Errorf("wanted endpoint %s, got %s", tc.endpoints[i], result.Service.Endpoint)
+				}
+			}
+
+			pending, err := box.Pending()
+			if err != nil {
+				t.Fatalf("could not list pending messages: %v", err)
+			}
+			if len(pending) != len(tc.endpoints) {
+				t.Errorf("wanted %d pending messages, got %d", len(tc.endpoints), len(pending))
+			}
+		})
+	}
+}
+
+func TestIsRouted(t *testing.T) {
+	card := &trello.Card{
+		ID:       "card",
+		Name:     "Buy milk",
+		IDList:   "list",
+		IDBoard:  "board",
+		IDLabels: []string{"a"},
+	}
+
+	tt := []struct {
+		name    string
+		service config.Service
+		event   entrello.Event
+		routed  bool
+	}{
+		{
+			name:    "service label without routes",
+			service: config.Service{Label: "a"},
+			routed:  true,
+		},
+		{
+			name:    "other label without routes",
+			service: config.Service{Label: "b"},
+			routed:  false,
+		},
+		{
+			name:    "label of the event",
+			service: config.Service{Routes: []config.Route{{Label: "b"}}},
+			event:   entrello.Event{LabelId: "b"},
+			routed:  true,
+		},
+		{
+			name:    "all criteria match",
+			service: config.Service{Routes: []config.Route{{Label: "a", List: "list", Board: "board", Name: "^Buy"}}},
+			routed:  true,
+		},
+		{
+			name:    "one criterion does not match",
+			service: config.Service{Routes: []config.Route{{List: "list", Name: "^Sell"}}},
+			routed:  false,
+		},
+		{
+			name:    "second route matches",
+			service: config.Service{Routes: []config.Route{{List: "other"}, {Board: "board"}}},
+			routed:  true,
+		},
+		{
+			name:    "route without criteria",
+			service: config.Service{Routes: []config.Route{{}}},
+			routed:  false,
+		},
+		{
+			name:    "invalid name regex",
+			service: config.Service{Routes: []config.Route{{Name: "("}}},
+			routed:  false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if routed := isRouted(tc.service, card, tc.event); routed != tc.routed {
+				t.Errorf("wanted %t, got %t", tc.routed, routed)
+			}
+		})
+	}
+}
+
+func TestPollServiceNotFound(t *testing.T) {
+	cfg := config.RunnerConfig{Services: []config.Service{{Name: "a"}}}
+	if _, err := PollService(context.Background(), cfg, "b"); !errors.Is(err, ErrServiceNotFound) {
+		t.Errorf("wanted ErrServiceNotFound, got %v", err)
+	}
+}
+
+func TestClaimBoard(t *testing.T) {
+	a, err := ClaimBoard("a")
+	if err != nil {
+		t.Fatalf("wanted to claim board a, got %v", err)
+	}
+	if _, err = ClaimBoard("a"); !errors.Is(err, ErrSyncInProgress) {
+		t.Errorf("wanted board a to be claimed already, got %v", err)
+	}
+	b, err := ClaimBoard("b")
+	if err != nil {
+		t.Errorf("wanted to claim board b while board a is claimed, got %v", err)
+	}
+
+	a.Release()
+	b.Release()
+	if a, err = ClaimBoard("a"); err != nil {
+		t.Errorf("wanted to claim board a after releasing it, got %v", err)
+	}
+
+	// releasing the old claim again must not release the new one
+	b.Release()
+	if _, err = ClaimBoard("a"); !errors.Is(err, ErrSyncInProgress) {
+		t.Errorf("wanted board a to be claimed still, got %v", err)
+	}
+	a.Release()
+}
+
+func TestPollWithClaim(t *testing.T) {
+	claim, err := ClaimBoard("c")
+	if err != nil {
+		t.Fatalf("could not claim board c: %v", err)
+	}
+
+	// the claim is released even if the sync fails before polling any services
+	cfg := config.RunnerConfig{TimezoneLocation: "Nowhere/Invalid", Trello: config.Trello{BoardId: "c"}}
+	if _, err = PollWithProgress(context.Background(), cfg, claim, nil); err == nil {
+		t.Errorf("wanted an error for the invalid timezone")
+	}
+
+	if claim, err = ClaimBoard("c"); err != nil {
+		t.Fatalf("wanted board c to be released, got %v", err)
+	}
+	claim.Release()
+}
+
+func TestNotifiedCard(t *testing.T) {
+	tt := []struct {
+		name string
+		body string
+		card string
+	}{
+		{name: "card", body: `{"id":"c1","name":"Buy milk"}`, card: "Buy milk"},
+		{name: "event envelope", body: `{"version":1,"card":{"id":"c1","name":"Buy milk"}}`, card: "Buy milk"},
+		{name: "invalid body", body: `{`, card: ""},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if card := notifiedCard([]byte(tc.body)); card != tc.card {
+				t.Errorf("wanted card '%s', got '%s'", tc.card, card)
+			}
+		})
+	}
+}
+
+func TestDeliverRecordsHistory(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	service := config.Service{Name: "history", Endpoint: server.URL}
+	msg := outbox.Message{Event: entrello.EventCardArchived, Body: []byte(`{"name":"Buy milk"}`)}
+
+	// failed deliveries are not recorded as notified
+	if err := Deliver(context.Background(), msg, service, entrello.Client{}); err == nil {
+		t.Fatalf("wanted the delivery to fail")
+	}
+	for _, event := range history.Default.Events() {
+		if event.Service == "history" {
+			t.Fatalf("wanted no events for the failed delivery, got %v", event)
+		}
+	}
+
+	status = http.StatusOK
+	if err := Deliver(context.Background(), msg, service, entrello.Client{}); err != nil {
+		t.Fatalf("could not deliver: %v", err)
+	}
+	event := history.Default.Events()[0]
+	if event.Type != history.EventNotified || event.Service != "history" || event.Card != "Buy milk" {
+		t.Errorf("wanted a notified event for the delivered card, got %v", event)
+	}
+}
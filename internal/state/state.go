@@ -0,0 +1,98 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/utkuufuk/entrello/pkg/trello"
+)
+
+// Service represents the state of an incrementally polled service, i.e. the cursor returned by
+// the latest successful poll and the set of items merged from all deltas received so far
+type Service struct {
+	Cursor string        `json:"cursor"`
+	Cards  []trello.Card `json:"cards"`
+}
+
+// Store is a concurrency-safe collection of service states keyed by service label ID,
+// persisted as a JSON file
+type Store struct {
+	path     string
+	mu       sync.Mutex
+	dirty    bool
+	services map[string]Service
+}
+
+// Load reads the store from the given file path, and returns an empty store if the file does not exist
+func Load(path string) (*Store, error) {
+	s := &Store{path: path, services: make(map[string]Service)}
+
+	b, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read state file: %w", err)
+	}
+
+	if err = json.Unmarshal(b, &s.services); err != nil {
+		return nil, fmt.Errorf("could not decode state file: %w", err)
+	}
+	return s, nil
+}
+
+// Get returns the state of the service with the given label ID
+func (s *Store) Get(label string) Service {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.services[label]
+}
+
+// Set updates the state of the service with the given label ID
+func (s *Store) Set(label string, service Service) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.services[label] = service
+	s.dirty = true
+}
+
+// Save writes the store to its file atomically, unless nothing has changed since it was loaded
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+
+	b, err := json.Marshal(s.services)
+	if err != nil {
+		return fmt.Errorf("could not encode state: %w", err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("could not create temporary state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not write state file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("could not write state file: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("could not replace state file: %w", err)
+	}
+
+	s.dirty = false
+	return nil
+}
@@ -0,0 +1,39 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/adlio/trello"
+	entrello "github.com/utkuufuk/entrello/pkg/trello"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	store, err := Load(path)
+	if err != nil {
+		t.Fatalf("could not load missing state file: %v", err)
+	}
+	if s := store.Get("label"); s.Cursor != "" || len(s.Cards) != 0 {
+		t.Errorf("wanted empty state, got %v", s)
+	}
+
+	store.Set("label", Service{Cursor: "c1", Cards: []entrello.Card{&trello.Card{Name: "a"}}})
+	if err = store.Save(); err != nil {
+		t.Fatalf("could not save state: %v", err)
+	}
+
+	store, err = Load(path)
+	if err != nil {
+		t.Fatalf("could not load state file: %v", err)
+	}
+
+	s := store.Get("label")
+	if s.Cursor != "c1" {
+		t.Errorf("wanted cursor 'c1', got '%s'", s.Cursor)
+	}
+	if len(s.Cards) != 1 || s.Cards[0].Name != "a" {
+		t.Errorf("wanted a single card named 'a', got %v", s.Cards)
+	}
+}
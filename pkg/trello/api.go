@@ -2,6 +2,7 @@ package trello
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/adlio/trello"
 	"golang.org/x/exp/slices"
@@ -10,31 +11,187 @@ import (
 // DeleteCard deletes a Trello card
 func (c Client) DeleteCard(card Card) error {
 	path := fmt.Sprintf("cards/%s", card.ID)
-	return c.api.Delete(path, trello.Defaults(), card)
+	return c.redact(c.api.Delete(path, trello.Defaults(), card))
 }
 
 // CreateCard creates a Trello card
 func (c Client) CreateCard(card Card, label string, listId string) error {
 	card.IDLabels = []string{label}
 	card.IDList = listId
-	return c.api.CreateCard(card, trello.Defaults())
+	return c.redact(c.api.CreateCard(card, trello.Defaults()))
+}
+
+// AddComment adds a comment to a Trello card
+func (c Client) AddComment(cardId, text string) error {
+	path := fmt.Sprintf("cards/%s/actions/comments", cardId)
+	return c.redact(c.api.Post(path, trello.Arguments{"text": text}, &trello.Action{}))
+}
+
+// SetDueDate sets the due date of a Trello card, or removes it if dueDate is nil
+func (c Client) SetDueDate(cardId string, dueDate *time.Time) error {
+	due := "null"
+	if dueDate != nil {
+		due = dueDate.Format(time.RFC3339)
+	}
+	return c.updateCard(cardId, trello.Arguments{"due": due})
+}
+
+// UnarchiveCard sends an archived Trello card back to the board
+func (c Client) UnarchiveCard(cardId string) error {
+	return c.updateCard(cardId, trello.Arguments{"closed": "false"})
+}
+
+// MoveCard moves a Trello card to the given list
+func (c Client) MoveCard(cardId, listId string) error {
+	return c.updateCard(cardId, trello.Arguments{"idList": listId})
+}
+
+func (c Client) updateCard(cardId string, args trello.Arguments) error {
+	path := fmt.Sprintf("cards/%s", cardId)
+	return c.redact(c.api.Put(path, args, &trello.Card{}))
+}
+
+// IsOnBoard checks whether the given card belongs to the board of the client
+func (c Client) IsOnBoard(card Card) bool {
+	return card != nil && card.IDBoard == c.boardId
+}
+
+// HasList checks whether the list with the given ID belongs to the board of the client
+func (c Client) HasList(listId string) (bool, error) {
+	list, err := c.api.GetList(listId, trello.Defaults())
+	if err != nil {
+		return false, c.redact(err)
+	}
+	return list.IDBoard == c.boardId, nil
 }
 
 // GetCard fetches a Trello card by its ID
 func (c Client) GetCard(id string) (Card, error) {
-	return c.api.GetCard(id, trello.Defaults())
+	card, err := c.api.GetCard(id, trello.Defaults())
+	return card, c.redact(err)
+}
+
+// CreateWebhook creates a webhook for the board with the given callback URL and description
+func (c Client) CreateWebhook(callbackUrl, description string) (Webhook, error) {
+	webhook := &trello.Webhook{
+		IDModel:     c.boardId,
+		CallbackURL: callbackUrl,
+		Description: description,
+	}
+	if err := c.api.CreateWebhook(webhook); err != nil {
+		return nil, c.redact(err)
+	}
+	return webhook, nil
+}
+
+// GetWebhooks fetches all webhooks that belong to the API token
+func (c Client) GetWebhooks() ([]Webhook, error) {
+	path := fmt.Sprintf("tokens/%s/webhooks", c.api.Token)
+	var webhooks []*trello.Webhook
+	if err := c.api.Get(path, trello.Defaults(), &webhooks); err != nil {
+		return nil, c.redact(err)
+	}
+
+	result := make([]Webhook, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		result = append(result, webhook)
+	}
+	return result, nil
+}
+
+// DeleteWebhook deletes a webhook by its ID
+func (c Client) DeleteWebhook(id string) error {
+	path := fmt.Sprintf("webhooks/%s", id)
+	return c.redact(c.api.Delete(path, trello.Defaults(), &trello.Webhook{}))
+}
+
+// UpdateWebhook updates the callback URL of a webhook by its ID and activates it
+func (c Client) UpdateWebhook(id, callbackUrl string) (Webhook, error) {
+	path := fmt.Sprintf("webhooks/%s", id)
+	args := trello.Arguments{"callbackURL": callbackUrl, "active": "true"}
+	webhook := &trello.Webhook{}
+	if err := c.api.Put(path, args, webhook); err != nil {
+		return nil, c.redact(err)
+	}
+	return webhook, nil
+}
+
+// EnsureWebhook makes sure that an active webhook exists for the board with the given callback URL,
+// either by creating a new one or by repairing an existing one with the same description. Returns the
+// webhook along with the action taken, which is one of the WebhookAction* constants.
+func (c Client) EnsureWebhook(callbackUrl, description string) (Webhook, string, error) {
+	webhooks, err := c.GetWebhooks()
+	if err != nil {
+		return nil, "", fmt.Errorf("could not list webhooks: %w", err)
+	}
+
+	webhook, action := planWebhook(webhooks, c.boardId, callbackUrl, description)
+	switch action {
+	case WebhookActionCreate:
+		webhook, err = c.CreateWebhook(callbackUrl, description)
+	case WebhookActionRepair:
+		webhook, err = c.UpdateWebhook(webhook.ID, callbackUrl)
+	}
+	if err != nil {
+		return nil, action, fmt.Errorf("could not %s webhook: %w", action, err)
+	}
+	return webhook, action, nil
+}
+
+// planWebhook decides which action should be taken so that an active webhook exists for the board with
+// the given callback URL. An existing webhook for the board with the same callback URL is repaired if
+// inactive, otherwise one with the same description is repaired by pointing it to the callback URL,
+// and a new webhook is created if neither exists.
+func planWebhook(webhooks []Webhook, boardId, callbackUrl, description string) (Webhook, string) {
+	var candidate Webhook
+	for _, webhook := range webhooks {
+		if webhook.IDModel != boardId {
+			continue
+		}
+		if webhook.CallbackURL == callbackUrl {
+			if webhook.Active {
+				return webhook, WebhookActionNone
+			}
+			return webhook, WebhookActionRepair
+		}
+		if candidate == nil && webhook.Description == description {
+			candidate = webhook
+		}
+	}
+
+	if candidate != nil {
+		return candidate, WebhookActionRepair
+	}
+	return nil, WebhookActionCreate
+}
+
+// CheckBoard checks whether the board of the client can be read with the API key and token of the client
+func (c Client) CheckBoard() error {
+	if _, err := c.api.GetBoard(c.boardId, trello.Defaults()); err != nil {
+		return fmt.Errorf("could not get board data: %w", c.redact(err))
+	}
+	return nil
+}
+
+// GetMemberId fetches the ID of the Trello member that owns the API token
+func (c Client) GetMemberId() (string, error) {
+	member, err := c.api.GetMember("me", trello.Defaults())
+	if err != nil {
+		return "", c.redact(err)
+	}
+	return member.ID, nil
 }
 
 // LoadBoard retrieves existing cards from the board that have at least one of the given label IDs
 func (c Client) LoadBoard(labels []string) error {
 	board, err := c.api.GetBoard(c.boardId, trello.Defaults())
 	if err != nil {
-		return fmt.Errorf("could not get board data: %w", err)
+		return fmt.Errorf("could not get board data: %w", c.redact(err))
 	}
 
 	cards, err := board.GetCards(trello.Defaults())
 	if err != nil {
-		return fmt.Errorf("could not fetch cards in board: %w", err)
+		return fmt.Errorf("could not fetch cards in board: %w", c.redact(err))
 	}
 
 	c.setExistingCards(cards, labels)
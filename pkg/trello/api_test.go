@@ -98,3 +98,77 @@ func newTestCardByLabel(labels []string) *trello.Card {
 		IDLabels: labels,
 	}
 }
+
+func TestPlanWebhook(t *testing.T) {
+	url := "https://entrello.example.com/trello-webhook"
+	tt := []struct {
+		name     string
+		webhooks []Webhook
+		action   string
+		id       string
+	}{
+		{
+			name:     "no webhooks",
+			webhooks: []Webhook{},
+			action:   WebhookActionCreate,
+		},
+		{
+			name: "active webhook with the same callback URL",
+			webhooks: []Webhook{
+				newTestWebhook("w1", "board", url, "entrello", true),
+			},
+			action: WebhookActionNone,
+			id:     "w1",
+		},
+		{
+			name: "inactive webhook with the same callback URL",
+			webhooks: []Webhook{
+				newTestWebhook("w1", "board", url, "other", false),
+			},
+			action: WebhookActionRepair,
+			id:     "w1",
+		},
+		{
+			name: "webhook with the same description pointing to an old URL",
+			webhooks: []Webhook{
+				newTestWebhook("w1", "board", "https://other.example.com", "other", true),
+				newTestWebhook("w2", "board", "https://old.example.com", "entrello", true),
+			},
+			action: WebhookActionRepair,
+			id:     "w2",
+		},
+		{
+			name: "webhooks of other boards and apps only",
+			webhooks: []Webhook{
+				newTestWebhook("w1", "other-board", url, "entrello", true),
+				newTestWebhook("w2", "board", "https://other.example.com", "other", true),
+			},
+			action: WebhookActionCreate,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			webhook, action := planWebhook(tc.webhooks, "board", url, "entrello")
+			if action != tc.action {
+				t.Errorf("wanted action '%s', got '%s'", tc.action, action)
+			}
+			if tc.id == "" && webhook != nil {
+				t.Errorf("wanted no webhook, got %s", webhook.ID)
+			}
+			if tc.id != "" && (webhook == nil || webhook.ID != tc.id) {
+				t.Errorf("wanted webhook %s, got %v", tc.id, webhook)
+			}
+		})
+	}
+}
+
+func newTestWebhook(id, boardId, callbackUrl, description string, active bool) Webhook {
+	return &trello.Webhook{
+		ID:          id,
+		IDModel:     boardId,
+		CallbackURL: callbackUrl,
+		Description: description,
+		Active:      active,
+	}
+}
@@ -0,0 +1,64 @@
+package trello
+
+import (
+	"time"
+)
+
+// EnvelopeVersion is the version of the event envelope format, which will be incremented
+// upon any backwards incompatible change
+const EnvelopeVersion = 1
+
+// EnvelopeModel represents a Trello board, list or member within an event envelope
+type EnvelopeModel struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// EventEnvelope represents the JSON structure of the notifications sent to services that have opted
+// into the envelope format, as opposed to the legacy format which consists of the card only. The event
+// specific fields are omitted unless they apply to the event type, i.e. the comment of comment_added,
+// the label of label_added and label_removed, and the previous list of card_moved.
+type EventEnvelope struct {
+	Version    int            `json:"version"`
+	Type       string         `json:"type"`
+	ActionId   string         `json:"action_id"`
+	Date       time.Time      `json:"date"`
+	Actor      EnvelopeModel  `json:"actor"`
+	Board      EnvelopeModel  `json:"board"`
+	List       EnvelopeModel  `json:"list"`
+	ListBefore *EnvelopeModel `json:"list_before,omitempty"`
+	Label      *EnvelopeModel `json:"label,omitempty"`
+	Comment    string         `json:"comment,omitempty"`
+	Labels     []string       `json:"labels"`
+	Card       Card           `json:"card"`
+}
+
+// NewEventEnvelope wraps the given card in an event envelope along with the event metadata
+func NewEventEnvelope(event Event, card Card) EventEnvelope {
+	labels := make([]string, 0, len(card.Labels))
+	for _, label := range card.Labels {
+		labels = append(labels, label.Name)
+	}
+
+	envelope := EventEnvelope{
+		Version:  EnvelopeVersion,
+		Type:     event.Type,
+		ActionId: event.ActionId,
+		Date:     event.Date,
+		Actor:    EnvelopeModel{Id: event.ActorId, Name: event.ActorName},
+		Board:    EnvelopeModel{Id: event.BoardId, Name: event.BoardName},
+		List:     EnvelopeModel{Id: event.ListId, Name: event.ListName},
+		Labels:   labels,
+		Card:     card,
+	}
+
+	switch event.Type {
+	case EventCardMoved:
+		envelope.ListBefore = &EnvelopeModel{Id: event.ListBeforeId, Name: event.ListBefore}
+	case EventLabelAdded, EventLabelRemoved:
+		envelope.Label = &EnvelopeModel{Id: event.LabelId, Name: event.LabelName}
+	case EventCommentAdded:
+		envelope.Comment = event.Comment
+	}
+	return envelope
+}
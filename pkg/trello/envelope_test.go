@@ -0,0 +1,69 @@
+package trello
+
+import (
+	"testing"
+
+	"github.com/adlio/trello"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewEventEnvelope(t *testing.T) {
+	card := &trello.Card{ID: "c1", Labels: []*trello.Label{{Name: "habit"}, {Name: "daily"}}}
+	base := Event{
+		ActionId:  "a1",
+		ActorId:   "m1",
+		ActorName: "Jane Doe",
+		CardId:    "c1",
+		BoardId:   "b1",
+		BoardName: "Tasks",
+		ListId:    "l2",
+		ListName:  "Done",
+		// the fields that don't apply to the event type are ignored
+		ListBeforeId: "l1",
+		ListBefore:   "Doing",
+		LabelId:      "x1",
+		LabelName:    "habit",
+		Comment:      "Nice",
+	}
+
+	tt := []struct {
+		eventType  string
+		listBefore *EnvelopeModel
+		label      *EnvelopeModel
+		comment    string
+	}{
+		{eventType: EventCardArchived},
+		{eventType: EventCardUnarchived},
+		{eventType: EventCardMoved, listBefore: &EnvelopeModel{Id: "l1", Name: "Doing"}},
+		{eventType: EventDueCompleted},
+		{eventType: EventLabelAdded, label: &EnvelopeModel{Id: "x1", Name: "habit"}},
+		{eventType: EventLabelRemoved, label: &EnvelopeModel{Id: "x1", Name: "habit"}},
+		{eventType: EventCommentAdded, comment: "Nice"},
+		{eventType: EventCardCreated},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.eventType, func(t *testing.T) {
+			event := base
+			event.Type = tc.eventType
+
+			want := EventEnvelope{
+				Version:    EnvelopeVersion,
+				Type:       tc.eventType,
+				ActionId:   "a1",
+				Actor:      EnvelopeModel{Id: "m1", Name: "Jane Doe"},
+				Board:      EnvelopeModel{Id: "b1", Name: "Tasks"},
+				List:       EnvelopeModel{Id: "l2", Name: "Done"},
+				ListBefore: tc.listBefore,
+				Label:      tc.label,
+				Comment:    tc.comment,
+				Labels:     []string{"habit", "daily"},
+				Card:       card,
+			}
+			opts := cmp.Comparer(func(x, y Card) bool { return x == y })
+			if diff := cmp.Diff(want, NewEventEnvelope(event, card), opts); diff != "" {
+				t.Errorf("envelope mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package trello
+
+import "strings"
+
+const redacted = "<redacted>"
+
+// redactedError is an error whose message has been stripped of the Trello API credentials
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e redactedError) Error() string {
+	return e.msg
+}
+
+func (e redactedError) Unwrap() error {
+	return e.err
+}
+
+// redact removes the API key and token of the client from the given error message, since the Trello
+// API client includes the request URLs, which contain the credentials, in its errors
+func (c Client) redact(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := err.Error()
+	for _, secret := range []string{c.api.Key, c.api.Token} {
+		if secret != "" {
+			msg = strings.ReplaceAll(msg, secret, redacted)
+		}
+	}
+
+	if msg == err.Error() {
+		return err
+	}
+	return redactedError{msg, err}
+}
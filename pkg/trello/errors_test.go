@@ -0,0 +1,53 @@
+package trello
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/utkuufuk/entrello/internal/config"
+)
+
+func TestRedact(t *testing.T) {
+	c := NewClient(config.Trello{ApiKey: "k3y", ApiToken: "t0ken"})
+	inner := errors.New("connection refused")
+
+	tt := []struct {
+		name string
+		err  error
+		msg  string
+	}{
+		{
+			name: "nil error",
+		},
+		{
+			name: "error without credentials",
+			err:  inner,
+			msg:  "connection refused",
+		},
+		{
+			name: "error with credentials",
+			err:  fmt.Errorf("GET /1/tokens/t0ken/webhooks?key=k3y&token=t0ken: %w", inner),
+			msg:  "GET /1/tokens/<redacted>/webhooks?key=<redacted>&token=<redacted>: connection refused",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := c.redact(tc.err)
+			if tc.err == nil {
+				if err != nil {
+					t.Errorf("wanted nil, got %v", err)
+				}
+				return
+			}
+
+			if err.Error() != tc.msg {
+				t.Errorf("wanted '%s', got '%s'", tc.msg, err.Error())
+			}
+			if !errors.Is(err, inner) {
+				t.Errorf("wanted the redacted error to wrap the original error")
+			}
+		})
+	}
+}
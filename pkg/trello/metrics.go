@@ -0,0 +1,62 @@
+package trello
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/utkuufuk/entrello/internal/metrics"
+)
+
+var (
+	apiCallsTotal = metrics.NewCounter(
+		"entrello_trello_api_calls_total",
+		"Number of Trello API calls by resource and method.",
+		"resource", "method",
+	)
+	apiErrorsTotal = metrics.NewCounter(
+		"entrello_trello_api_errors_total",
+		"Number of failed Trello API calls by resource, method and status code, which is 0 for network errors.",
+		"resource", "method", "code",
+	)
+	apiCallDuration = metrics.NewHistogram(
+		"entrello_trello_api_call_duration_seconds",
+		"Latency of the Trello API calls by resource.",
+		metrics.DefaultBuckets,
+		"resource",
+	)
+)
+
+// instrumentedTransport records metrics for each Trello API call made through the underlying transport
+type instrumentedTransport struct {
+	next http.RoundTripper
+}
+
+func (t instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resource := apiResource(req.URL.Path)
+	apiCallsTotal.Inc(resource, req.Method)
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	apiCallDuration.Since(start, resource)
+
+	if err != nil {
+		apiErrorsTotal.Inc(resource, req.Method, "0")
+		return resp, err
+	}
+	if resp.StatusCode >= 400 {
+		apiErrorsTotal.Inc(resource, req.Method, strconv.Itoa(resp.StatusCode))
+	}
+	return resp, nil
+}
+
+// apiResource returns the type of the resource in the given Trello API path without any IDs
+// to keep the number of time series low, e.g. "cards" for "/1/cards/<id>/actions/comments"
+func apiResource(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) > 1 && parts[0] == "1" {
+		return parts[1]
+	}
+	return "unknown"
+}
@@ -0,0 +1,24 @@
+package trello
+
+import "testing"
+
+func TestApiResource(t *testing.T) {
+	tt := []struct {
+		path     string
+		resource string
+	}{
+		{path: "/1/cards/abc/actions/comments", resource: "cards"},
+		{path: "/1/boards/abc", resource: "boards"},
+		{path: "/1/tokens/abc/webhooks", resource: "tokens"},
+		{path: "/1", resource: "unknown"},
+		{path: "/", resource: "unknown"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.path, func(t *testing.T) {
+			if resource := apiResource(tc.path); resource != tc.resource {
+				t.Errorf("wanted resource '%s', got '%s'", tc.resource, resource)
+			}
+		})
+	}
+}
@@ -1,8 +1,12 @@
 package trello
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/adlio/trello"
 	"github.com/utkuufuk/entrello/internal/config"
@@ -10,6 +14,21 @@ import (
 
 type Card *trello.Card
 
+type Webhook *trello.Webhook
+
+const (
+	WebhookActionNone   = "keep"
+	WebhookActionCreate = "create"
+	WebhookActionRepair = "repair"
+)
+
+const (
+	MaxNameLength        = 16384
+	MaxDescriptionLength = 16384
+	MinDueYear           = 1970
+	MaxDueYear           = 2100
+)
+
 type Client struct {
 	api           *trello.Client
 	boardId       string
@@ -17,25 +36,73 @@ type Client struct {
 }
 
 func NewClient(cfg config.Trello) Client {
+	api := trello.NewClient(cfg.ApiKey, cfg.ApiToken)
+	api.Client = &http.Client{
+		Timeout:   config.DefaultTimeout,
+		Transport: instrumentedTransport{http.DefaultTransport},
+	}
 	return Client{
-		api:           trello.NewClient(cfg.ApiKey, cfg.ApiToken),
+		api:           api,
 		boardId:       cfg.BoardId,
 		existingCards: make(map[string][]Card),
 	}
 }
 
+// WithContext returns a copy of the client whose API calls are made with the given context, so that
+// they are aborted once the context is cancelled or its deadline is exceeded. The copy shares the
+// existing cards loaded from the board with the original client.
+func (c Client) WithContext(ctx context.Context) Client {
+	c.api = c.api.WithContext(ctx)
+	return c
+}
+
 // NewCard creates a new Trello card model with the given mandatory fields name,
 // and the optional description and dueDate fields
 func NewCard(name, description string, dueDate *time.Time) (card Card, err error) {
-	if name == "" {
-		return card, fmt.Errorf("card name cannot be blank")
-	}
-
-	return &trello.Card{
+	card = &trello.Card{
 		Name: name,
 		Desc: description,
 		Due:  dueDate,
-	}, nil
+	}
+	if err = ValidateCard(card); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+// ValidateCard checks whether the given card has a non-blank name, a name and description within
+// the length limits imposed by Trello, and a due date (if any) within a sane range
+func ValidateCard(card Card) error {
+	if card == nil {
+		return fmt.Errorf("card cannot be null")
+	}
+
+	if strings.TrimSpace(card.Name) == "" {
+		return fmt.Errorf("card name cannot be blank")
+	}
+
+	if n := utf8.RuneCountInString(card.Name); n > MaxNameLength {
+		return fmt.Errorf("card name cannot be longer than %d characters, got %d", MaxNameLength, n)
+	}
+
+	if n := utf8.RuneCountInString(card.Desc); n > MaxDescriptionLength {
+		return fmt.Errorf(
+			"card description cannot be longer than %d characters, got %d",
+			MaxDescriptionLength,
+			n,
+		)
+	}
+
+	if card.Due != nil && (card.Due.Year() < MinDueYear || card.Due.Year() > MaxDueYear) {
+		return fmt.Errorf(
+			"card due date must be between years %d and %d, got %s",
+			MinDueYear,
+			MaxDueYear,
+			card.Due.Format(time.RFC3339),
+		)
+	}
+
+	return nil
 }
 
 // FilterNewAndStale compares the given cards with the existing cards and returns two arrays;
@@ -2,17 +2,22 @@ package trello
 
 import (
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/adlio/trello"
 	"github.com/google/go-cmp/cmp"
 )
 
 func TestNewCard(t *testing.T) {
+	due := time.Date(2022, time.Month(4), 1, 0, 0, 0, 0, time.UTC)
+	ancient := time.Date(1, time.Month(1), 1, 0, 0, 0, 0, time.UTC)
 	tt := []struct {
 		name  string
 		cName string
 		cDesc string
+		cDue  *time.Time
 		err   error
 	}{
 		{
@@ -27,6 +32,35 @@ func TestNewCard(t *testing.T) {
 			cDesc: "desc",
 			err:   fmt.Errorf("card name cannot be blank"),
 		},
+		{
+			name:  "whitespace-only name",
+			cName: "  \t",
+			cDesc: "desc",
+			err:   fmt.Errorf("card name cannot be blank"),
+		},
+		{
+			name:  "name too long",
+			cName: strings.Repeat("a", MaxNameLength+1),
+			err:   fmt.Errorf("card name cannot be longer than 16384 characters, got 16385"),
+		},
+		{
+			name:  "description too long",
+			cName: "name",
+			cDesc: strings.Repeat("ü", MaxDescriptionLength+1),
+			err:   fmt.Errorf("card description cannot be longer than 16384 characters, got 16385"),
+		},
+		{
+			name:  "valid due date",
+			cName: "name",
+			cDue:  &due,
+			err:   nil,
+		},
+		{
+			name:  "zero due date",
+			cName: "name",
+			cDue:  &ancient,
+			err:   fmt.Errorf("card due date must be between years 1970 and 2100, got 0001-01-01T00:00:00Z"),
+		},
 	}
 
 	for _, tc := range tt {
@@ -36,7 +70,7 @@ func TestNewCard(t *testing.T) {
 				return (x == nil && y == nil) || (x.Error() == y.Error())
 			}))
 
-			_, err := NewCard(tc.cName, tc.cDesc, nil)
+			_, err := NewCard(tc.cName, tc.cDesc, tc.cDue)
 			if diff := cmp.Diff(err, tc.err, opts...); diff != "" {
 				t.Errorf("errors diff: %s", diff)
 			}
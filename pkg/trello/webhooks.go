@@ -4,36 +4,153 @@ import (
 	"crypto/hmac"
 	"crypto/sha1"
 	"encoding/base64"
+	"time"
 )
 
+const (
+	EventCardArchived   = "card_archived"
+	EventCardUnarchived = "card_unarchived"
+	EventCardMoved      = "card_moved"
+	EventDueCompleted   = "due_completed"
+	EventLabelAdded     = "label_added"
+	EventLabelRemoved   = "label_removed"
+	EventCommentAdded   = "comment_added"
+	EventCardCreated    = "card_created"
+)
+
+// EventTypes contains all the event types that can be parsed from webhook request bodies
+var EventTypes = []string{
+	EventCardArchived,
+	EventCardUnarchived,
+	EventCardMoved,
+	EventDueCompleted,
+	EventLabelAdded,
+	EventLabelRemoved,
+	EventCommentAdded,
+	EventCardCreated,
+}
+
+type webhookModel struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
 // WebhookRequestBody represents the JSON structure of a Trello webhook request body
 type WebhookRequestBody struct {
 	Action struct {
-		Type string `json:"type"`
+		Id              string    `json:"id"`
+		Type            string    `json:"type"`
+		Date            time.Time `json:"date"`
+		IdMemberCreator string    `json:"idMemberCreator"`
+
+		MemberCreator struct {
+			Id       string `json:"id"`
+			Username string `json:"username"`
+			FullName string `json:"fullName"`
+		} `json:"memberCreator"`
 
 		Display struct {
 			TranslationKey string `json:"translationKey"`
 		} `json:"display"`
 
 		Data struct {
+			Text       string       `json:"text"`
+			Board      webhookModel `json:"board"`
+			List       webhookModel `json:"list"`
+			ListBefore webhookModel `json:"listBefore"`
+			ListAfter  webhookModel `json:"listAfter"`
+
 			Card struct {
-				Id string `json:"id"`
+				Id          string `json:"id"`
+				Name        string `json:"name"`
+				Closed      bool   `json:"closed"`
+				DueComplete bool   `json:"dueComplete"`
 			} `json:"card"`
+
+			Old struct {
+				Closed      *bool `json:"closed"`
+				DueComplete *bool `json:"dueComplete"`
+			} `json:"old"`
+
+			Label struct {
+				Id    string `json:"id"`
+				Name  string `json:"name"`
+				Color string `json:"color"`
+			} `json:"label"`
 		} `json:"data"`
 	} `json:"action"`
 }
 
-// ParseArchivedCardId parses the archived card ID from the given webhook request body provided that
-// the request body represents an archived card event. Returns empty string otherwise.
-func ParseArchivedCardId(body WebhookRequestBody) string {
+// Event represents a card event parsed from a webhook request body
+type Event struct {
+	Type         string
+	ActionId     string
+	Date         time.Time
+	ActorId      string
+	ActorName    string
+	CardId       string
+	BoardId      string
+	BoardName    string
+	ListId       string
+	ListName     string
+	ListBeforeId string
+	ListBefore   string
+	LabelId      string
+	LabelName    string
+	Comment      string
+}
+
+// ParseEvent parses a card event from the given webhook request body. Returns false
+// if the request body does not represent any of the supported event types.
+func ParseEvent(body WebhookRequestBody) (event Event, ok bool) {
 	action := body.Action
-	actionType := action.Type
-	key := action.Display.TranslationKey
-	id := action.Data.Card.Id
-	if actionType == "updateCard" && key == "action_archived_card" {
-		return id
+	data := action.Data
+
+	event = Event{
+		ActionId:  action.Id,
+		Date:      action.Date,
+		ActorId:   action.IdMemberCreator,
+		ActorName: action.MemberCreator.FullName,
+		CardId:    data.Card.Id,
+		BoardId:   data.Board.Id,
+		BoardName: data.Board.Name,
+		ListId:    data.List.Id,
+		ListName:  data.List.Name,
+		LabelId:   data.Label.Id,
+		LabelName: data.Label.Name,
+	}
+
+	switch action.Type {
+	case "updateCard":
+		switch {
+		case action.Display.TranslationKey == "action_archived_card":
+			event.Type = EventCardArchived
+		case data.Old.Closed != nil && *data.Old.Closed && !data.Card.Closed:
+			event.Type = EventCardUnarchived
+		case data.ListAfter.Id != "" && data.ListBefore.Id != data.ListAfter.Id:
+			event.Type = EventCardMoved
+			event.ListId = data.ListAfter.Id
+			event.ListName = data.ListAfter.Name
+			event.ListBeforeId = data.ListBefore.Id
+			event.ListBefore = data.ListBefore.Name
+		case data.Old.DueComplete != nil && !*data.Old.DueComplete && data.Card.DueComplete:
+			event.Type = EventDueCompleted
+		}
+	case "addLabelToCard":
+		event.Type = EventLabelAdded
+	case "removeLabelFromCard":
+		event.Type = EventLabelRemoved
+	case "commentCard":
+		event.Type = EventCommentAdded
+		event.Comment = data.Text
+	case "createCard":
+		event.Type = EventCardCreated
+	}
+
+	if event.Type == "" || event.CardId == "" {
+		return event, false
 	}
-	return ""
+	return event, true
 }
 
 // VerifyWebhookSignature verifies the given Trello webhook signature (headerHash) by comparing it
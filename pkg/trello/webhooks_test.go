@@ -0,0 +1,128 @@
+package trello
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseEvent(t *testing.T) {
+	tt := []struct {
+		name      string
+		body      string
+		ok        bool
+		eventType string
+		listName  string
+		listFrom  string
+		labelId   string
+		comment   string
+	}{
+		{
+			name: "archived card",
+			body: `{"action": {"type": "updateCard", "display": {"translationKey": "action_archived_card"},
+				"data": {"card": {"id": "c1", "closed": true}, "old": {"closed": false}}}}`,
+			ok:        true,
+			eventType: EventCardArchived,
+		},
+		{
+			name: "unarchived card",
+			body: `{"action": {"type": "updateCard", "display": {"translationKey": "action_sent_card_to_board"},
+				"data": {"card": {"id": "c1", "closed": false}, "old": {"closed": true}}}}`,
+			ok:        true,
+			eventType: EventCardUnarchived,
+		},
+		{
+			name: "card moved between lists",
+			body: `{"action": {"type": "updateCard", "display": {"translationKey": "action_move_card_from_list_to_list"},
+				"data": {"card": {"id": "c1"}, "listBefore": {"id": "l1", "name": "Todo"},
+				"listAfter": {"id": "l2", "name": "Done"}, "old": {"idList": "l1"}}}}`,
+			ok:        true,
+			eventType: EventCardMoved,
+			listName:  "Done",
+			listFrom:  "l1",
+		},
+		{
+			name: "due date marked complete",
+			body: `{"action": {"type": "updateCard", "display": {"translationKey": "action_marked_the_due_date_complete"},
+				"data": {"card": {"id": "c1", "dueComplete": true}, "old": {"dueComplete": false}}}}`,
+			ok:        true,
+			eventType: EventDueCompleted,
+		},
+		{
+			name: "label added",
+			body: `{"action": {"type": "addLabelToCard",
+				"data": {"card": {"id": "c1"}, "label": {"id": "lb1", "name": "habit"}}}}`,
+			ok:        true,
+			eventType: EventLabelAdded,
+			labelId:   "lb1",
+		},
+		{
+			name: "label removed",
+			body: `{"action": {"type": "removeLabelFromCard",
+				"data": {"card": {"id": "c1"}, "label": {"id": "lb1", "name": "habit"}}}}`,
+			ok:        true,
+			eventType: EventLabelRemoved,
+			labelId:   "lb1",
+		},
+		{
+			name:      "comment added",
+			body:      `{"action": {"type": "commentCard", "data": {"card": {"id": "c1"}, "text": "nice"}}}`,
+			ok:        true,
+			eventType: EventCommentAdded,
+			comment:   "nice",
+		},
+		{
+			name:      "card created",
+			body:      `{"action": {"type": "createCard", "data": {"card": {"id": "c1"}, "list": {"id": "l1", "name": "Todo"}}}}`,
+			ok:        true,
+			eventType: EventCardCreated,
+			listName:  "Todo",
+		},
+		{
+			name: "card renamed",
+			body: `{"action": {"type": "updateCard", "display": {"translationKey": "action_renamed_card"},
+				"data": {"card": {"id": "c1"}, "old": {"name": "old"}}}}`,
+			ok: false,
+		},
+		{
+			name: "unsupported action type",
+			body: `{"action": {"type": "updateBoard", "data": {"board": {"id": "b1"}}}}`,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var body WebhookRequestBody
+			if err := json.Unmarshal([]byte(tc.body), &body); err != nil {
+				t.Fatalf("could not unmarshal request body: %v", err)
+			}
+
+			event, ok := ParseEvent(body)
+			if ok != tc.ok {
+				t.Fatalf("expected %t, got %t", tc.ok, ok)
+			}
+			if !ok {
+				return
+			}
+
+			if event.Type != tc.eventType {
+				t.Errorf("wanted event type '%s', got '%s'", tc.eventType, event.Type)
+			}
+			if event.CardId != "c1" {
+				t.Errorf("wanted card ID 'c1', got '%s'", event.CardId)
+			}
+			if event.ListName != tc.listName {
+				t.Errorf("wanted list name '%s', got '%s'", tc.listName, event.ListName)
+			}
+			if event.ListBeforeId != tc.listFrom {
+				t.Errorf("wanted previous list ID '%s', got '%s'", tc.listFrom, event.ListBeforeId)
+			}
+			if event.LabelId != tc.labelId {
+				t.Errorf("wanted label ID '%s', got '%s'", tc.labelId, event.LabelId)
+			}
+			if event.Comment != tc.comment {
+				t.Errorf("wanted comment '%s', got '%s'", tc.comment, event.Comment)
+			}
+		})
+	}
+}